@@ -0,0 +1,274 @@
+// Command nexusctl debugs Issuers that use the Nexus webhook: it resolves
+// zones the way the solver does, validates solver configs, and checks that
+// credentials can write to Nexus.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/pkg/solver"
+)
+
+const usage = `usage: nexusctl <command> [flags]
+
+Commands:
+  zone               show the zone and record a challenge name maps to
+  validate           check solver configs, or the Nexus solvers in an Issuer
+  check-credentials  create and delete a test record with an Issuer's API key
+
+Run nexusctl <command> -h for its flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "zone":
+		err = zone(os.Args[2:], os.Stdout)
+	case "validate":
+		err = validate(os.Args[2:], os.Stdout)
+	case "check-credentials":
+		err = checkCredentials(os.Args[2:], os.Stdout)
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// configFlags are the flags every command reads solver configs with.
+type configFlags struct {
+	config     *string
+	file       *string
+	groupName  *string
+	solverName *string
+	timeout    *time.Duration
+}
+
+func addConfigFlags(fs *flag.FlagSet) configFlags {
+	return configFlags{
+		config:     fs.String("config", "", "Solver config as JSON or YAML, as in an Issuer's webhook.config."),
+		file:       fs.String("f", "", `File holding a solver config or an Issuer or ClusterIssuer; "-" reads stdin.`),
+		groupName:  fs.String("group-name", "", "In an Issuer, only check webhook solvers with this groupName."),
+		solverName: fs.String("solver-name", "", "In an Issuer, only check webhook solvers with this solverName."),
+		timeout:    fs.Duration("timeout", time.Minute, "How long to allow for DNS and Nexus calls."),
+	}
+}
+
+// solverConfig is one config found in the input, with where it came from.
+type solverConfig struct {
+	source string
+	raw    []byte
+	// fromIssuer is set for configs read from an Issuer or ClusterIssuer.
+	fromIssuer bool
+	// ambient is whether cert-manager allows the issuer ambient
+	// credentials by default: ClusterIssuers yes, Issuers no.
+	ambient bool
+}
+
+func (f configFlags) load(stdin io.Reader) ([]solverConfig, error) {
+	var data []byte
+	switch {
+	case *f.config != "" && *f.file != "":
+		return nil, errors.New("--config and -f can't both be set")
+	case *f.config != "":
+		data = []byte(*f.config)
+	case *f.file == "-":
+		var err error
+		if data, err = io.ReadAll(stdin); err != nil {
+			return nil, err
+		}
+	case *f.file != "":
+		var err error
+		if data, err = os.ReadFile(*f.file); err != nil {
+			return nil, err
+		}
+	default:
+		return []solverConfig{{source: "empty config", raw: []byte("{}")}}, nil
+	}
+	return parseConfigs(data, *f.groupName, *f.solverName)
+}
+
+// parseConfigs returns the solver configs in data: the webhook solvers of
+// an Issuer or ClusterIssuer matching groupName and solverName, or data
+// itself if it's a solver config.
+func parseConfigs(data []byte, groupName, solverName string) ([]solverConfig, error) {
+	var meta struct {
+		Kind string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	if meta.Kind != cmapi.IssuerKind && meta.Kind != cmapi.ClusterIssuerKind {
+		raw, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid solver config: %w", err)
+		}
+		return []solverConfig{{source: "solver config", raw: raw}}, nil
+	}
+
+	var issuer cmapi.Issuer
+	if err := yaml.Unmarshal(data, &issuer); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", meta.Kind, err)
+	}
+	if issuer.Spec.ACME == nil {
+		return nil, fmt.Errorf("%s %s is not an ACME issuer", meta.Kind, issuer.Name)
+	}
+	var configs []solverConfig
+	for i, s := range issuer.Spec.ACME.Solvers {
+		if s.DNS01 == nil || s.DNS01.Webhook == nil {
+			continue
+		}
+		w := s.DNS01.Webhook
+		if groupName != "" && w.GroupName != groupName || solverName != "" && w.SolverName != solverName {
+			continue
+		}
+		raw := []byte("{}")
+		if w.Config != nil {
+			raw = w.Config.Raw
+		}
+		configs = append(configs, solverConfig{
+			source:     fmt.Sprintf("%s %s solvers[%d] (%s/%s)", meta.Kind, issuer.Name, i, w.GroupName, w.SolverName),
+			raw:        raw,
+			fromIssuer: true,
+			ambient:    meta.Kind == cmapi.ClusterIssuerKind,
+		})
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%s %s has no matching webhook solvers", meta.Kind, issuer.Name)
+	}
+	return configs, nil
+}
+
+func zone(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("zone", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	fqdn := fs.String("fqdn", "", "Name being certified, or its _acme-challenge name.")
+	fs.Parse(args)
+	if *fqdn == "" {
+		return errors.New("--fqdn is required")
+	}
+	configs, err := cf.load(os.Stdin)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *cf.timeout)
+	defer cancel()
+
+	c := solver.New()
+	for _, sc := range configs {
+		t, err := c.ResolveTarget(ctx, *fqdn, sc.raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sc.source, err)
+		}
+		fmt.Fprintf(w, "%s: record %q in zone %q (serves %s)\n", sc.source, t.Record, t.Zone, t.FQDN)
+	}
+	return nil
+}
+
+func validate(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	ambient := fs.Bool("allow-ambient-credentials", false, "For a bare solver config, whether its issuer may use ambient credentials.")
+	fs.Parse(args)
+	configs, err := cf.load(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	c := solver.New()
+	failed := 0
+	for _, sc := range configs {
+		if !sc.fromIssuer {
+			sc.ambient = *ambient
+		}
+		if err := c.ValidateConfig(sc.raw, sc.ambient); err != nil {
+			fmt.Fprintf(w, "%s: %v\n", sc.source, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "%s: ok\n", sc.source)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d configs are invalid", failed, len(configs))
+	}
+	return nil
+}
+
+func checkCredentials(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("check-credentials", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	fqdn := fs.String("fqdn", "", "Name to check writes for, as for zone.")
+	namespace := fs.String("namespace", "", "Namespace of the Issuer, where apikeysecret is read from.")
+	kubeconfig := fs.String("kubeconfig", os.Getenv("KUBECONFIG"), "Kubeconfig to read apikeysecret with. Defaults to $KUBECONFIG.")
+	keyFile := fs.String("api-key-file", "", "File holding the API key, for configs with ambient credentials. Defaults to $NEXUS_API_KEY.")
+	fs.Parse(args)
+	if *fqdn == "" {
+		return errors.New("--fqdn is required")
+	}
+	configs, err := cf.load(os.Stdin)
+	if err != nil {
+		return err
+	}
+	settings := solver.DefaultSettings()
+	settings.APIKeyFile = *keyFile
+	proc, err := solver.NewProcess(settings)
+	if err != nil {
+		return err
+	}
+
+	opts := []solver.Option{solver.WithProcess(proc)}
+	if *kubeconfig != "" {
+		restConfig, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+		if err != nil {
+			return err
+		}
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, solver.WithClient(client))
+		if *namespace == "" {
+			if *namespace, _, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+				&clientcmd.ClientConfigLoadingRules{ExplicitPath: *kubeconfig}, nil).Namespace(); err != nil {
+				return err
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *cf.timeout)
+	defer cancel()
+
+	c := solver.New(opts...)
+	for _, sc := range configs {
+		// Without an issuer to go by, allow ambient credentials, which is
+		// all a key from --api-key-file can be.
+		ambient := sc.ambient || !sc.fromIssuer
+		t, err := c.CheckCredentials(ctx, *fqdn, *namespace, sc.raw, ambient)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sc.source, err)
+		}
+		fmt.Fprintf(w, "%s: created and deleted a test record beside %q in zone %q\n", sc.source, t.Record, t.Zone)
+	}
+	return nil
+}
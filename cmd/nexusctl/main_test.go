@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const clusterIssuer = `
+apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: letsencrypt
+spec:
+  acme:
+    server: https://acme-v02.api.letsencrypt.org/directory
+    privateKeySecretRef:
+      name: letsencrypt
+    solvers:
+      - http01:
+          ingress: {}
+      - dns01:
+          webhook:
+            groupName: nexus.fudo.org
+            solverName: nexus
+            config:
+              service: svc
+      - dns01:
+          webhook:
+            groupName: other.example.com
+            solverName: other
+`
+
+func TestParseConfigs(t *testing.T) {
+	configs, err := parseConfigs([]byte(clusterIssuer), "nexus.fudo.org", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("expected the one Nexus solver, got %+v", configs)
+	}
+	sc := configs[0]
+	if string(sc.raw) != `{"service":"svc"}` || !sc.fromIssuer || !sc.ambient || !strings.Contains(sc.source, "solvers[1]") {
+		t.Errorf("unexpected config %+v (%s)", sc, sc.raw)
+	}
+
+	configs, err = parseConfigs([]byte("service: svc\nzoneName: example.com\n"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 || configs[0].fromIssuer || string(configs[0].raw) != `{"service":"svc","zoneName":"example.com"}` {
+		t.Errorf("expected a bare config to be converted to JSON, got %+v", configs)
+	}
+
+	if _, err = parseConfigs([]byte(clusterIssuer), "", "missing"); err == nil {
+		t.Error("expected an error when no solver matches")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var out strings.Builder
+	err := validate([]string{"--config", `{"service": "svc", "zoneName": "example.com", "typo": true}`}, &out)
+	if err == nil || !strings.Contains(out.String(), "typo") {
+		t.Errorf("expected the unknown field to be reported, got %v: %s", err, out.String())
+	}
+
+	out.Reset()
+	if err = validate([]string{"--config", `{"service": "svc"}`, "--allow-ambient-credentials"}, &out); err != nil {
+		t.Errorf("expected a valid config to pass, got %v: %s", err, out.String())
+	}
+}
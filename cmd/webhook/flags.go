@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/pkg/solver"
+)
+
+// settings are the solver settings given on the command line. They're read
+// before the webhook server parses flags, since the solvers are built
+// first; see parseSettings.
+var settings = solver.DefaultSettings()
+
+// showVersion is only registered so --version appears in the usage text;
+// see solver.VersionRequested.
+var showVersion = flag.Bool("version", false, "Print version information and exit.")
+
+func init() {
+	settings.StateNamespace = os.Getenv("POD_NAMESPACE")
+	settings.DefaultsNamespace = os.Getenv("POD_NAMESPACE")
+	settings.OTLPTracesEndpoint = defaultOTLPTracesEndpoint()
+	registerSettingsFlags(flag.CommandLine, &settings)
+}
+
+// registerSettingsFlags defines a flag on fs for each field of s, defaulting
+// to its current value.
+func registerSettingsFlags(fs *flag.FlagSet, s *solver.Settings) {
+	fs.StringVar(&s.LogFormat, "log-format", s.LogFormat, "Log output format, one of 'text' or 'json'.")
+	fs.StringVar(&s.AuditLog, "audit-log", s.AuditLog,
+		"File to append a JSON audit record to for every DNS record the solver creates or deletes. "+
+			"Audit records go to the log, under the \"audit\" logger, if empty.")
+	fs.BoolVar(&s.EmitEvents, "emit-events", s.EmitEvents,
+		"Record Present and CleanUp failures as Events on the affected Challenge.")
+
+	fs.StringVar(&s.APIKeyFile, "api-key-file", s.APIKeyFile,
+		"File holding a Nexus API key to use when a challenge has no apikeysecret and allows ambient credentials.")
+	fs.StringVar(&s.CredentialCommand, "credential-command", s.CredentialCommand,
+		"Command run by the external credential provider; it must print the Nexus API key on stdout.")
+	fs.Var((*listFlag)(&s.AllowedSecretNamespaces), "allowed-secret-namespaces",
+		"Comma-separated namespaces that apikeysecret may read Secrets from besides the issuer's own.")
+	fs.BoolVar(&s.SecretInformer, "secret-informer", s.SecretInformer,
+		"Serve API key Secrets from an informer cache instead of reading them on every challenge.")
+	fs.Var((*listFlag)(&s.RBACPreflightNamespaces), "rbac-preflight-namespaces",
+		"Comma-separated namespaces, besides --allowed-secret-namespaces, to check at startup that the webhook can read Secrets in.")
+	fs.BoolVar(&s.RBACPreflightStrict, "rbac-preflight-strict", s.RBACPreflightStrict,
+		"Fail startup, instead of only logging, when the RBAC preflight finds missing permissions.")
+
+	fs.StringVar(&s.StateConfigMap, "state-configmap", s.StateConfigMap,
+		"Name of a ConfigMap used to persist challenge record IDs across restarts. Disabled if empty.")
+	fs.BoolVar(&s.StateCRD, "state-crd", s.StateCRD,
+		"Persist challenge record IDs as NexusChallenge resources instead of in --state-configmap.")
+	fs.StringVar(&s.StateNamespace, "state-namespace", s.StateNamespace,
+		"Namespace of the state ConfigMap or NexusChallenge resources. Defaults to $POD_NAMESPACE.")
+	fs.DurationVar(&s.OrphanGCInterval, "orphan-gc-interval", s.OrphanGCInterval,
+		"How often to delete stored records whose Challenge no longer exists. Requires --state-configmap or --state-crd. Disabled if zero.")
+	fs.DurationVar(&s.OrphanGCMinAge, "orphan-gc-min-age", s.OrphanGCMinAge,
+		"Only delete orphaned records presented at least this long ago.")
+	fs.DurationVar(&s.RecordVerifyInterval, "record-verify-interval", s.RecordVerifyInterval,
+		"How often to check that presented records are still served, creating them again if they vanished. Disabled if zero.")
+
+	fs.StringVar(&s.DefaultsConfigMap, "defaults-configmap", s.DefaultsConfigMap,
+		"ConfigMap whose defaults.json key holds config fields every solver applies under its own defaults and the Issuer's config. Changes are picked up without a restart.")
+	fs.StringVar(&s.DefaultsNamespace, "defaults-namespace", s.DefaultsNamespace,
+		"Namespace of --defaults-configmap. Defaults to $POD_NAMESPACE.")
+	fs.BoolVar(&s.IssuerDefaults, "issuer-defaults", s.IssuerDefaults,
+		"Apply config fields from the NexusIssuerDefaults named after each challenge's issuer, in the challenge's resource namespace.")
+	fs.StringVar(&s.ConfigEnvPrefix, "config-env-prefix", s.ConfigEnvPrefix,
+		"Solver config may refer to webhook environment variables with this prefix as ${NAME}, so one Issuer works across environments. Empty disables expansion.")
+
+	fs.Var((*listFlag)(&s.AllowedZones), "allowed-zones",
+		"Comma-separated zones the webhook may create records in. Any zone if empty.")
+	fs.Var((*listFlag)(&s.DeniedZones), "denied-zones",
+		"Comma-separated zones the webhook never creates records in, even if they are within an allowed zone.")
+	fs.StringVar(&s.TenantPolicyFile, "tenant-policy", s.TenantPolicyFile,
+		"JSON file of rules limiting which zones and Nexus services each namespace's issuers may use. Re-read on SIGHUP. Unrestricted if empty.")
+
+	fs.Float64Var(&s.NexusQPS, "nexus-qps", s.NexusQPS,
+		"Maximum sustained rate of Nexus API calls per second, across all challenges. Zero disables the limit.")
+	fs.IntVar(&s.NexusBurst, "nexus-burst", s.NexusBurst,
+		"Maximum burst of Nexus API calls above --nexus-qps.")
+	fs.IntVar(&s.MaxConcurrentChallenges, "max-concurrent-challenges", s.MaxConcurrentChallenges,
+		"Maximum Nexus API calls in flight at once, across all challenges, so mass renewals don't overwhelm a small Nexus. Zero means no limit.")
+	fs.BoolVar(&s.SerializeZoneWrites, "serialize-zone-writes", s.SerializeZoneWrites,
+		"Send at most one create or delete to each Nexus zone at a time, so bursts of renewals don't conflict.")
+	fs.DurationVar(&s.ClientCacheTTL, "client-cache-ttl", s.ClientCacheTTL,
+		"How long to reuse a Nexus client for the same domain, service and key. Zero disables caching.")
+	fs.IntVar(&s.FailureStreakWarning, "failure-streak-warning", s.FailureStreakWarning,
+		"Log a warning each time this many Present or CleanUp calls in a row fail for one zone. Zero disables the warning.")
+
+	fs.DurationVar(&s.NexusCallTimeout, "nexus-call-timeout", s.NexusCallTimeout,
+		"Maximum time to wait for a single Nexus API call.")
+	fs.DurationVar(&s.KubeCallTimeout, "kube-call-timeout", s.KubeCallTimeout,
+		"Maximum time to wait for a single Kubernetes API call.")
+	fs.DurationVar(&s.PresentTimeout, "present-timeout", s.PresentTimeout,
+		"Maximum time a whole Present may take, retries included, unless the issuer sets presentTimeout. Zero means no limit.")
+	fs.DurationVar(&s.CleanupTimeout, "cleanup-timeout", s.CleanupTimeout,
+		"Maximum time a whole CleanUp may take, retries included, unless the issuer sets cleanupTimeout. Zero means no limit.")
+	fs.DurationVar(&s.DrainTimeout, "drain-timeout", s.DrainTimeout,
+		"How long to wait on shutdown for in-flight Present and CleanUp calls to finish.")
+
+	fs.StringVar(&s.PreferIPFamily, "prefer-ip-family", s.PreferIPFamily,
+		"IPv4 or IPv6: reach nameservers over this address family where they have one, e.g. IPv6 in IPv6-only clusters. Empty leaves the choice to the resolver.")
+	fs.DurationVar(&s.ZoneCacheTTL, "zone-cache-ttl", s.ZoneCacheTTL,
+		"How long to remember the zone found for a name. Zero disables the zone cache.")
+	fs.DurationVar(&s.ZoneCacheNegativeTTL, "zone-cache-negative-ttl", s.ZoneCacheNegativeTTL,
+		"How long to remember that no zone could be found for a name.")
+	fs.IntVar(&s.ZoneCacheSize, "zone-cache-size", s.ZoneCacheSize,
+		"Most names to remember zones for.")
+
+	fs.StringVar(&s.MetricsAddress, "metrics-bind-address", s.MetricsAddress,
+		"Address to serve Prometheus metrics and build info (/version) on. Disabled if empty.")
+	fs.StringVar(&s.MetricsCertFile, "metrics-tls-cert-file", s.MetricsCertFile,
+		"Certificate to serve metrics over HTTPS with, separately from the webhook's. Plain HTTP if empty.")
+	fs.StringVar(&s.MetricsKeyFile, "metrics-tls-private-key-file", s.MetricsKeyFile,
+		"Private key for --metrics-tls-cert-file.")
+	fs.StringVar(&s.HealthProbeAddress, "health-probe-bind-address", s.HealthProbeAddress,
+		"Address to serve /healthz and /readyz over plain HTTP on. Disabled if empty.")
+	fs.StringVar(&s.ReadinessNexusURL, "readiness-nexus-url", s.ReadinessNexusURL,
+		"Nexus URL /readyz requests to check that Nexus is reachable. Not checked if empty.")
+	fs.BoolVar(&s.StartupCredentialCheck, "startup-credential-check", s.StartupCredentialCheck,
+		"Stay not ready until the API key for at least one solver's default service can be read.")
+	fs.StringVar(&s.PprofAddress, "pprof-bind-address", s.PprofAddress,
+		"Loopback address to serve net/http/pprof on, e.g. 127.0.0.1:6060; reach it with kubectl port-forward. Disabled if empty.")
+	fs.StringVar(&s.DebugAddress, "debug-bind-address", s.DebugAddress,
+		"Address to serve /debug/challenges and /debug/flags/v on. Requires --debug-token-file. Disabled if empty.")
+	fs.StringVar(&s.DebugTokenFile, "debug-token-file", s.DebugTokenFile,
+		"File holding the bearer token /debug requests must present.")
+	fs.StringVar(&s.OTLPTracesEndpoint, "otlp-traces-endpoint", s.OTLPTracesEndpoint,
+		"OTLP/HTTP URL to export traces to. Defaults to $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, "+
+			"or $OTEL_EXPORTER_OTLP_ENDPOINT/v1/traces. Tracing is disabled if empty.")
+}
+
+func defaultOTLPTracesEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// listFlag is a comma-separated list flag. Blank entries are dropped.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
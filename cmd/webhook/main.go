@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/pkg/solver"
+)
+
+// groupName, like solverConfig, is read before the webhook server parses
+// flags, since the server is started for that API group.
+var groupName = flag.String("group-name", os.Getenv("GROUP_NAME"),
+	"API group the solvers are served under, matching the groupName in Issuers. Defaults to $GROUP_NAME.")
+
+// solverConfig is read before the webhook server parses flags, since it
+// decides which solvers the server is started with; see flagValue.
+var solverConfig = flag.String("solver-config", "",
+	`JSON file mapping solver names to their default configs, e.g. {"nexus": {}, "nexus-staging": {"service": "staging"}}. Serves a single "nexus" solver if empty.`)
+
+// testConnection is read before the webhook server parses flags, since it
+// replaces the server with a one-off check; see runTestConnection.
+var testConnection = flag.String("test-connection", "",
+	"Create and delete a throwaway TXT record in this Nexus zone with each solver's default config, then exit, non-zero on failure. For init-container smoke tests.")
+
+// testConnectionTimeout bounds the whole --test-connection check.
+const testConnectionTimeout = 2 * time.Minute
+
+func main() {
+	if solver.VersionRequested() {
+		fmt.Println(solver.BuildInfo())
+		return
+	}
+	if action, ok := solver.StandaloneAction(); ok {
+		if err := solver.RunStandalone(action, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == solver.ActionGenManifests {
+		if err := solver.GenManifests(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == solver.ActionGenSchema {
+		if err := solver.GenSchema(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if zone := flagValue(os.Args[1:], "test-connection"); zone != "" {
+		if err := runTestConnection(zone, os.Args[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	group, err := resolveGroupName(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	proc, err := parseSettings(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	solvers, err := loadSolvers(flagValue(os.Args[1:], "solver-config"), solver.WithGroupName(group), solver.WithProcess(proc))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Args = append(os.Args[:1], withDelegatedKubeconfig(os.Args[1:])...)
+
+	// The webhook command only parses its own pflags; hand it ours too.
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+
+	hooks := make([]webhook.Solver, len(solvers))
+	for i, s := range solvers {
+		hooks[i] = s
+	}
+	cmd.RunWebhookServer(group, hooks...)
+	for _, s := range solvers {
+		s.Drain()
+	}
+}
+
+// resolveGroupName returns the API group from --group-name in args, or
+// $GROUP_NAME if the flag isn't given.
+func resolveGroupName(args []string) (string, error) {
+	group := flagValue(args, "group-name")
+	if group == "" {
+		group = *groupName
+	}
+	if group == "" {
+		return "", errors.New("no API group: set --group-name or $GROUP_NAME to the groupName used in Issuers")
+	}
+	if errs := validation.IsDNS1123Subdomain(group); len(errs) > 0 {
+		return "", fmt.Errorf("invalid API group %q: %s", group, strings.Join(errs, "; "))
+	}
+	return group, nil
+}
+
+// parseSettings parses our flags in args into settings, ignoring those
+// only the webhook server knows, and returns the Process the solvers
+// share.
+func parseSettings(args []string) (*solver.Process, error) {
+	fs := pflag.NewFlagSet("nexus-webhook", pflag.ContinueOnError)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	// The webhook server prints the usage for --help.
+	fs.Usage = func() {}
+	if err := fs.Parse(args); err != nil && !errors.Is(err, pflag.ErrHelp) {
+		return nil, err
+	}
+	return solver.NewProcess(settings)
+}
+
+// runTestConnection round-trips a record in zone for every solver. It
+// takes the same args as the webhook server, so an init container can
+// share the webhook container's; flags only the server knows are ignored.
+func runTestConnection(zone string, args []string, w io.Writer) error {
+	proc, err := parseSettings(args)
+	if err != nil {
+		return err
+	}
+
+	opts := []solver.Option{solver.WithProcess(proc)}
+	restConfig, err := rest.InClusterConfig()
+	if kubeconfig := flagValue(args, "kubeconfig"); kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	switch {
+	case err == nil:
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, solver.WithClient(client))
+	case !errors.Is(err, rest.ErrNotInCluster):
+		return err
+	}
+	// Outside a cluster only ambient credentials are available.
+
+	solvers, err := loadSolvers(*solverConfig, opts...)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testConnectionTimeout)
+	defer cancel()
+	for _, s := range solvers {
+		t, err := s.TestConnection(ctx, zone)
+		if err != nil {
+			return fmt.Errorf("solver %s: %w", s.Name(), err)
+		}
+		fmt.Fprintf(w, "solver %s: created and deleted a test record in zone %q\n", s.Name(), t.Zone)
+	}
+	return nil
+}
+
+// loadSolvers builds one solver per entry in the --solver-config file, in
+// name order, or the default solver if path is empty. Each is built with
+// opts.
+func loadSolvers(path string, opts ...solver.Option) ([]*solver.Solver, error) {
+	if path == "" {
+		return []*solver.Solver{solver.New(opts...)}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read solver config: %w", err)
+	}
+	defaults := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("invalid solver config %s: %w", path, err)
+	}
+	if len(defaults) == 0 {
+		return nil, fmt.Errorf("solver config %s defines no solvers", path)
+	}
+
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	solvers := make([]*solver.Solver, len(names))
+	for i, name := range names {
+		solvers[i] = solver.New(append([]solver.Option{solver.WithName(name), solver.WithDefaultConfig(defaults[name])}, opts...)...)
+	}
+	return solvers, nil
+}
+
+// withDelegatedKubeconfig makes --kubeconfig also apply to the delegated
+// authentication and authorization checks, unless they're given their own
+// kubeconfigs. Outside a cluster those checks have no in-cluster config to
+// fall back on, so this is all a developer needs to run the webhook
+// locally against a dev cluster.
+func withDelegatedKubeconfig(args []string) []string {
+	kubeconfig := flagValue(args, "kubeconfig")
+	if kubeconfig == "" {
+		return args
+	}
+	for _, name := range []string{"authentication-kubeconfig", "authorization-kubeconfig"} {
+		if flagValue(args, name) == "" {
+			args = append(args, "--"+name+"="+kubeconfig)
+		}
+	}
+	return args
+}
+
+// flagValue returns the value of the flag called name in args, accepting
+// the forms cobra does: --name=value, --name value, and the same with a
+// single dash.
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		trimmed := strings.TrimLeft(arg, "-")
+		if len(arg)-len(trimmed) == 0 || len(arg)-len(trimmed) > 2 {
+			continue
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(trimmed, name+"=") {
+			return strings.TrimPrefix(trimmed, name+"=")
+		}
+	}
+	return ""
+}
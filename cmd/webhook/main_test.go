@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFlagValue(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"--solver-config=/etc/solvers.json"}, expected: "/etc/solvers.json"},
+		{args: []string{"--v", "2", "--solver-config", "/etc/solvers.json"}, expected: "/etc/solvers.json"},
+		{args: []string{"-solver-config=a.json"}, expected: "a.json"},
+		{args: []string{"--solver-config-other=a.json"}, expected: ""},
+		{args: []string{"--", "--solver-config=a.json"}, expected: ""},
+		{args: []string{"--solver-config"}, expected: ""},
+	}
+	for _, test := range tests {
+		if v := flagValue(test.args, "solver-config"); v != test.expected {
+			t.Errorf("flagValue(%q) = %q, expected %q", test.args, v, test.expected)
+		}
+	}
+}
+
+func TestWithDelegatedKubeconfig(t *testing.T) {
+	args := withDelegatedKubeconfig([]string{"--kubeconfig", "dev.yaml", "--authorization-kubeconfig=authz.yaml"})
+	expected := []string{"--kubeconfig", "dev.yaml", "--authorization-kubeconfig=authz.yaml", "--authentication-kubeconfig=dev.yaml"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+	if args := withDelegatedKubeconfig([]string{"--v=2"}); !reflect.DeepEqual(args, []string{"--v=2"}) {
+		t.Errorf("expected args without --kubeconfig to be unchanged, got %q", args)
+	}
+}
+
+func TestLoadSolvers(t *testing.T) {
+	solvers, err := loadSolvers("")
+	if err != nil || len(solvers) != 1 || solvers[0].Name() != "nexus" {
+		t.Fatalf("expected the default solver, got %v, %v", solvers, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "solvers.json")
+	config := `{"nexus-staging": {"service": "staging"}, "nexus": {"service": "prod"}}`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	solvers, err = loadSolvers(path)
+	if err != nil {
+		t.Fatalf("loadSolvers: %v", err)
+	}
+	var names []string
+	for _, s := range solvers {
+		names = append(names, s.Name())
+	}
+	if len(names) != 2 || names[0] != "nexus" || names[1] != "nexus-staging" {
+		t.Errorf("expected solvers nexus and nexus-staging, got %v", names)
+	}
+
+	if err := os.WriteFile(path, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSolvers(path); err == nil {
+		t.Errorf("expected an empty solver config to be rejected")
+	}
+}
+
+func TestResolveGroupName(t *testing.T) {
+	prev := *groupName
+	defer func() { *groupName = prev }()
+	*groupName = "from-env.example.com"
+
+	tests := []struct {
+		args     []string
+		expected string
+		fails    bool
+	}{
+		{args: nil, expected: "from-env.example.com"},
+		{args: []string{"--group-name=acme.example.com"}, expected: "acme.example.com"},
+		{args: []string{"--group-name", "Not_A_Group"}, fails: true},
+	}
+	for _, test := range tests {
+		group, err := resolveGroupName(test.args)
+		if test.fails {
+			if err == nil {
+				t.Errorf("resolveGroupName(%q): expected an error", test.args)
+			}
+			continue
+		}
+		if err != nil || group != test.expected {
+			t.Errorf("resolveGroupName(%q) = %q, %v; expected %q", test.args, group, err, test.expected)
+		}
+	}
+
+	*groupName = ""
+	if _, err := resolveGroupName(nil); err == nil {
+		t.Errorf("expected a missing group name to be an error")
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	prev := settings
+	defer func() { settings = prev }()
+
+	args := []string{"--allowed-zones", "example.com, ,example.net", "--secure-port=8443", "--nexus-qps=2"}
+	if _, err := parseSettings(args); err != nil {
+		t.Fatalf("parseSettings: %v", err)
+	}
+	if !reflect.DeepEqual(settings.AllowedZones, []string{"example.com", "example.net"}) || settings.NexusQPS != 2 {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+
+	if _, err := parseSettings([]string{"--log-format=xml"}); err == nil {
+		t.Errorf("expected an unknown log format to be rejected")
+	}
+}
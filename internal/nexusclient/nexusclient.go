@@ -0,0 +1,50 @@
+// Package nexusclient is the part of the Nexus API the solver uses, and the
+// adapter from nexus-go to it.
+package nexusclient
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/fudoniten/nexus-go/nexus"
+	"github.com/fudoniten/nexus-go/nexus/challenge"
+)
+
+// API creates and deletes challenge records. Tests swap in the in-memory
+// implementation from the nexustest package.
+type API interface {
+	CreateChallengeRecord(name, value string) (uuid.UUID, error)
+	DeleteChallengeRecord(id uuid.UUID) error
+}
+
+// NewFunc builds an API for a Nexus domain and service.
+type NewFunc func(domain, service string, key []byte) (API, error)
+
+const V1 = "v1"
+
+// Versions maps each Nexus API version the solver can speak to the
+// constructor for its adapter.
+var Versions = map[string]NewFunc{
+	V1: New,
+}
+
+// client adapts a nexus-go client to API.
+type client struct {
+	nexus *nexus.NexusClient
+}
+
+// New returns an API backed by nexus-go.
+func New(domain, service string, key []byte) (API, error) {
+	c, err := nexus.New(domain, service, key)
+	if err != nil {
+		return nil, err
+	}
+	return &client{c}, nil
+}
+
+func (c *client) CreateChallengeRecord(name, value string) (uuid.UUID, error) {
+	return challenge.CreateChallengeRecord(c.nexus, name, value)
+}
+
+func (c *client) DeleteChallengeRecord(id uuid.UUID) error {
+	return challenge.DeleteChallengeRecord(c.nexus, id)
+}
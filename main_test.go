@@ -1,28 +0,0 @@
-package main
-
-import (
-	"os"
-	"testing"
-
-	"github.com/jetstack/cert-manager/test/acme/dns"
-)
-
-var (
-	zone = os.Getenv("TEST_ZONE_NAME")
-)
-
-func TestRunsSuite(t *testing.T) {
-	// The manifest path should contain a file named config.json that is a
-	// snippet of valid configuration that should be included on the
-	// ChallengeRequest passed as part of the test cases.
-	//
-
-	fixture := dns.NewFixture(&nexusDnsProviderSolver{},
-		dns.SetResolvedZone(zone),
-		dns.SetAllowAmbientCredentials(false),
-		dns.SetManifestPath("testdata/nexus"),
-		dns.SetBinariesPath("_test/kubebuilder/bin"),
-	)
-
-	fixture.RunConformance(t)
-}
@@ -0,0 +1,156 @@
+// Package nexustest provides an in-memory stand-in for the Nexus challenge
+// record API, so the solver can be exercised without Nexus credentials.
+// Tests can inspect the records it holds and every call made against it.
+package nexustest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned for rejected calls. The text mirrors the HTTP status the
+// real API responds with, so the solver classifies them the same way.
+var (
+	ErrUnauthorized = errors.New("401 Unauthorized")
+	ErrNotFound     = errors.New("404 Not Found")
+)
+
+// Record is a challenge TXT record held by the server.
+type Record struct {
+	Domain  string
+	Service string
+	Name    string
+	Value   string
+}
+
+// Request is a call made against the server.
+type Request struct {
+	Op      string
+	Domain  string
+	Service string
+	Name    string
+	Value   string
+	ID      uuid.UUID
+}
+
+const (
+	OpCreate = "CreateChallengeRecord"
+	OpDelete = "DeleteChallengeRecord"
+)
+
+// Server holds challenge records for any number of domains and services.
+type Server struct {
+	// Keys, if set, lists the API keys accepted; clients built with any
+	// other key fail with ErrUnauthorized.
+	Keys [][]byte
+	// Delay, if set, is how long each call takes to return after it has
+	// taken effect, like a response held up on the way back.
+	Delay time.Duration
+	// CreateDelay, if set, holds up creates by this much more than Delay.
+	CreateDelay time.Duration
+
+	lock     sync.Mutex
+	records  map[uuid.UUID]Record
+	requests []Request
+	failures []error
+}
+
+func NewServer() *Server {
+	return &Server{records: map[uuid.UUID]Record{}}
+}
+
+// Client returns a client for domain and service, with the same signature
+// as the constructor the solver uses for the real API.
+func (s *Server) Client(domain, service string, key []byte) (*Client, error) {
+	if len(s.Keys) > 0 && !s.accepts(key) {
+		return nil, ErrUnauthorized
+	}
+	return &Client{server: s, domain: domain, service: service}, nil
+}
+
+func (s *Server) accepts(key []byte) bool {
+	for _, k := range s.Keys {
+		if string(k) == string(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// FailNext makes the next len(errs) calls fail with errs, in order, without
+// changing any records.
+func (s *Server) FailNext(errs ...error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = append(s.failures, errs...)
+}
+
+// Records returns the records currently held.
+func (s *Server) Records() map[uuid.UUID]Record {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records := make(map[uuid.UUID]Record, len(s.records))
+	for id, r := range s.records {
+		records[id] = r
+	}
+	return records
+}
+
+// Requests returns every call made so far, including failed ones.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// record logs req and returns the injected failure for it, if any. The
+// caller must hold the lock.
+func (s *Server) record(req Request) error {
+	s.requests = append(s.requests, req)
+	if len(s.failures) == 0 {
+		return nil
+	}
+	err := s.failures[0]
+	s.failures = s.failures[1:]
+	return err
+}
+
+// Client makes calls against a Server for one domain and service.
+type Client struct {
+	server  *Server
+	domain  string
+	service string
+}
+
+func (c *Client) CreateChallengeRecord(name, value string) (uuid.UUID, error) {
+	s := c.server
+	defer time.Sleep(s.Delay + s.CreateDelay)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.record(Request{Op: OpCreate, Domain: c.domain, Service: c.service, Name: name, Value: value}); err != nil {
+		return uuid.Nil, err
+	}
+	id := uuid.New()
+	s.records[id] = Record{Domain: c.domain, Service: c.service, Name: name, Value: value}
+	return id, nil
+}
+
+func (c *Client) DeleteChallengeRecord(id uuid.UUID) error {
+	s := c.server
+	defer time.Sleep(s.Delay)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.record(Request{Op: OpDelete, Domain: c.domain, Service: c.service, ID: id}); err != nil {
+		return err
+	}
+	r, ok := s.records[id]
+	if !ok || r.Domain != c.domain || r.Service != c.service {
+		return fmt.Errorf("challenge record %s: %w", id, ErrNotFound)
+	}
+	delete(s.records, id)
+	return nil
+}
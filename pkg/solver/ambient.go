@@ -0,0 +1,47 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func init() {
+	registerCredentialProvider(providerFile, func(c *Solver) CredentialProvider { return fileProvider{c} })
+	registerCredentialProvider(providerEnv, func(*Solver) CredentialProvider { return envProvider{} })
+}
+
+// fileProvider reads the key from the APIKeyFile. The file is re-read on
+// every call so keys rotated by a CSI driver or secret syncer are picked up.
+type fileProvider struct {
+	c *Solver
+}
+
+func (fileProvider) Ambient() bool { return true }
+
+func (p fileProvider) APIKey(context.Context, *v1alpha1.ChallengeRequest, *Config) (string, error) {
+	path := p.c.proc.settings.APIKeyFile
+	if path == "" {
+		return "", errors.New("no ambient api key file: set --api-key-file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read api key file: %w", err)
+	}
+	return string(data), nil
+}
+
+// envProvider reads the key from $NEXUS_API_KEY.
+type envProvider struct{}
+
+func (envProvider) Ambient() bool { return true }
+
+func (envProvider) APIKey(context.Context, *v1alpha1.ChallengeRequest, *Config) (string, error) {
+	if key := os.Getenv("NEXUS_API_KEY"); key != "" {
+		return key, nil
+	}
+	return "", errors.New("no ambient api key: set --api-key-file or $NEXUS_API_KEY")
+}
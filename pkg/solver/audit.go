@@ -0,0 +1,111 @@
+package solver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+const (
+	auditCreate = "create"
+	auditDelete = "delete"
+
+	auditSucceeded = "succeeded"
+	auditFailed    = "failed"
+)
+
+// auditRecord describes one create or delete the solver asked Nexus for.
+// The record value is hashed: it is a short-lived secret, but the hash
+// still lets it be matched against the Challenge it came from.
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	Solver    string    `json:"solver"`
+	RequestID string    `json:"requestId,omitempty"`
+	Namespace string    `json:"namespace"`
+	DNSName   string    `json:"dnsName"`
+	Zone      string    `json:"zone"`
+	Record    string    `json:"record"`
+	ValueHash string    `json:"valueHash"`
+	RecordID  string    `json:"recordId,omitempty"`
+}
+
+// auditLog writes audit records to the AuditLog file, or to log if it isn't
+// set. It is shared by every solver in the process.
+type auditLog struct {
+	log logr.Logger
+
+	once    sync.Once
+	openErr error
+
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// open opens path for appending, unless it's empty. Only the first call has
+// any effect.
+func (a *auditLog) open(path string) error {
+	a.once.Do(func() {
+		if path == "" {
+			return
+		}
+		a.w, a.openErr = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	})
+	return a.openErr
+}
+
+func (a *auditLog) write(r auditRecord) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.w == nil {
+		a.log.Info("dns record "+r.Action+" "+r.Result,
+			"solver", r.Solver, "requestId", r.RequestID, "namespace", r.Namespace, "dnsName", r.DNSName,
+			"zone", r.Zone, "record", r.Record, "valueHash", r.ValueHash, "recordId", r.RecordID, "error", r.Error)
+		return
+	}
+	line, err := json.Marshal(r)
+	if err == nil {
+		_, err = a.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		a.log.Error(err, "could not write audit record", "action", r.Action, "record", r.Record, "zone", r.Zone)
+	}
+}
+
+// auditMutation records the outcome of asking Nexus to create or delete the record
+// for ch. A zero id means Nexus didn't return one.
+func (c *Solver) auditMutation(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, target challengeTarget, id uuid.UUID, err error) {
+	valueHash := sha256.Sum256([]byte(ch.Key))
+	r := auditRecord{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Result:    auditSucceeded,
+		Solver:    c.Name(),
+		RequestID: requestIDFrom(ctx),
+		Namespace: ch.ResourceNamespace,
+		DNSName:   ch.DNSName,
+		Zone:      target.domain,
+		Record:    target.record,
+		ValueHash: hex.EncodeToString(valueHash[:]),
+	}
+	if id != uuid.Nil {
+		r.RecordID = id.String()
+	}
+	if err != nil {
+		r.Result = auditFailed
+		r.Error = err.Error()
+	}
+	c.proc.audit.write(r)
+}
@@ -0,0 +1,46 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	c, _, ch := newTestSolver(t, "")
+	var buf bytes.Buffer
+	c.proc.audit.w = &buf
+	ch.UID = "req-1"
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if err := c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+
+	var records []auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r auditRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a create and a delete record, got %+v", records)
+	}
+	for i, action := range []string{auditCreate, auditDelete} {
+		r := records[i]
+		if r.Action != action || r.Result != auditSucceeded || r.RequestID != "req-1" ||
+			r.Namespace != "default" || r.Zone != "example.com" || r.Record != "_acme-challenge.www" || r.RecordID == "" {
+			t.Errorf("unexpected %s record %+v", action, r)
+		}
+		if r.ValueHash == "" || strings.Contains(buf.String(), `"token"`) {
+			t.Errorf("expected the record value to be hashed, got %+v", r)
+		}
+	}
+	if records[0].RecordID != records[1].RecordID {
+		t.Errorf("expected the delete to name the created record, got %s and %s", records[0].RecordID, records[1].RecordID)
+	}
+}
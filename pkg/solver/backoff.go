@@ -0,0 +1,79 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// retryLaterError means Nexus asked the webhook to back off, or is still
+// failing after the retries ran out. cert-manager only sees the error, so
+// the hint is part of the reason it shows, and until it passes the solver
+// answers calls for the same service and zone without going to Nexus.
+type retryLaterError struct {
+	after time.Duration
+	err   error
+}
+
+func (e *retryLaterError) Error() string {
+	return fmt.Sprintf("%v; retry in %s", e.err, e.after.Round(time.Second))
+}
+
+func (e *retryLaterError) Unwrap() error { return e.err }
+
+// nexusCooldowns holds, for each service and zone, when the solver may
+// call Nexus again.
+type nexusCooldowns struct {
+	lock  sync.Mutex
+	until map[string]time.Time
+}
+
+// withRetryHint runs withRetry for the Nexus service and zone in key, and
+// turns throttling into a retryLaterError, remembering the backoff it asks
+// for so cert-manager's own retries don't hammer Nexus meanwhile.
+func (c *Solver) withRetryHint(ctx context.Context, key string, cfg retryConfig, retryable func(error) bool, log logr.Logger, op func() error) (err error) {
+	c.cooldowns.lock.Lock()
+	until, cooling := c.cooldowns.until[key]
+	c.cooldowns.lock.Unlock()
+	if remaining := time.Until(until); cooling && remaining > 0 {
+		return &retryLaterError{after: remaining, err: fmt.Errorf("%w: backing off after Nexus asked to retry later", ErrNexusUnavailable)}
+	}
+
+	err = withRetry(ctx, cfg, retryable, log, op)
+	after, ok := retryHint(err, cfg)
+	if !ok {
+		return
+	}
+	log.Info("nexus asked to back off", "retryAfter", after, "error", err.Error())
+	c.cooldowns.lock.Lock()
+	if c.cooldowns.until == nil {
+		c.cooldowns.until = make(map[string]time.Time)
+	}
+	c.cooldowns.until[key] = time.Now().Add(after)
+	c.cooldowns.lock.Unlock()
+	return &retryLaterError{after: after, err: err}
+}
+
+// retryHint returns how long to wait before calling Nexus again after
+// err: what the error asks for if it knows, or the longest retry backoff
+// if Nexus throttled the webhook or kept failing. Timeouts give no hint,
+// since they say nothing about when Nexus will recover.
+func retryHint(err error, cfg retryConfig) (time.Duration, bool) {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return 0, false
+	}
+	var hinted interface{ RetryAfter() time.Duration }
+	if errors.As(err, &hinted) && hinted.RetryAfter() > 0 {
+		return hinted.RetryAfter(), true
+	}
+	code, _ := statusCode(err)
+	if code == http.StatusTooManyRequests || errors.Is(err, ErrNexusUnavailable) {
+		return cfg.backoff().Cap, true
+	}
+	return 0, false
+}
@@ -0,0 +1,25 @@
+package solver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRetryHint(t *testing.T) {
+	c, server, ch := newTestSolver(t, `{"retry": {"maxAttempts": 1, "maxBackoff": "30s"}}`)
+	server.FailNext(errors.New("429 Too Many Requests"))
+	err := c.Present(ch)
+	var retryLater *retryLaterError
+	if !errors.As(err, &retryLater) || err.Error() != "nexus: busy, retry in 30s" {
+		t.Fatalf("expected a retry hint from the throttled call, got %v", err)
+	}
+
+	calls := len(server.Requests())
+	if err := c.Present(ch); !errors.As(err, &retryLater) || !strings.HasPrefix(err.Error(), "nexus: busy, retry in") {
+		t.Errorf("expected Present to keep backing off, got %v", err)
+	}
+	if got := len(server.Requests()); got != calls {
+		t.Errorf("expected no Nexus calls while backing off, got %d more", got-calls)
+	}
+}
@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// Run with make bench. Each iteration presents and cleans up one
+// challenge against the in-memory Nexus server, so the numbers are the
+// webhook's own overhead: config decoding, credentials, client caching,
+// locking and bookkeeping.
+
+// benchmarkSolver returns a solver and a function making the i'th
+// challenge to present with it.
+func benchmarkSolver(b *testing.B) (*Solver, func(i int64) *v1alpha1.ChallengeRequest) {
+	b.Helper()
+	c, _, base := newTestSolver(b, "")
+	c.proc.setLogger(logr.Discard())
+	// Measure the webhook, not the client-side Nexus rate limit.
+	c.proc.limiter = nil
+	// Track challenges in memory, as a single replica does by default.
+	c.store = nil
+	c.clients.ttl = c.proc.settings.ClientCacheTTL
+	return c, func(i int64) *v1alpha1.ChallengeRequest {
+		ch := *base
+		ch.DNSName = fmt.Sprintf("host%d.example.com", i)
+		ch.ResolvedFQDN = "_acme-challenge." + ch.DNSName + "."
+		ch.Key = fmt.Sprintf("token-%d", i)
+		return &ch
+	}
+}
+
+func reportChallengeRate(b *testing.B) {
+	if s := b.Elapsed().Seconds(); s > 0 {
+		b.ReportMetric(float64(b.N)/s, "challenges/s")
+	}
+}
+
+func BenchmarkPresentCleanUp(b *testing.B) {
+	c, benchmarkChallenge := benchmarkSolver(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch := benchmarkChallenge(int64(i))
+		if err := c.Present(ch); err != nil {
+			b.Fatal(err)
+		}
+		if err := c.CleanUp(ch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportChallengeRate(b)
+}
+
+// BenchmarkPresentCleanUpParallel models a renewal burst: many challenges
+// in one zone at once, which contend on the zone and client cache locks.
+func BenchmarkPresentCleanUpParallel(b *testing.B) {
+	c, benchmarkChallenge := benchmarkSolver(b)
+	var next atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch := benchmarkChallenge(next.Add(1))
+			if err := c.Present(ch); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := c.CleanUp(ch); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	reportChallengeRate(b)
+}
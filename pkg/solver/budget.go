@@ -0,0 +1,79 @@
+package solver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// budgetRetention is how long a challenge's failures are remembered after
+// the last one, for challenges cert-manager gives up on without calling
+// CleanUp.
+const budgetRetention = 24 * time.Hour
+
+// challengeBudget counts the failed Present calls for one challenge.
+type challengeBudget struct {
+	attempts int
+	first    time.Time
+	last     time.Time
+	err      error
+}
+
+// retryBudgets tracks how many of each challenge's retries have been
+// spent, so a challenge that keeps failing stops reaching Nexus once
+// retry.challengeAttempts or retry.challengeTimeout runs out.
+type retryBudgets struct {
+	lock    sync.Mutex
+	entries map[ChallengeKey]*challengeBudget
+}
+
+// check returns an error wrapping ErrRetryBudgetExhausted if ck has spent
+// the budget in cfg.
+func (b *retryBudgets) check(ck ChallengeKey, cfg retryConfig) error {
+	if cfg.ChallengeAttempts == 0 && cfg.ChallengeTimeout == nil {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	e, ok := b.entries[ck]
+	if !ok {
+		return nil
+	}
+	elapsed := time.Since(e.first)
+	if cfg.ChallengeAttempts > 0 && e.attempts >= cfg.ChallengeAttempts ||
+		cfg.ChallengeTimeout != nil && elapsed >= cfg.ChallengeTimeout.Duration {
+		return fmt.Errorf("%w: gave up after %d failed attempts over %s; last error: %w",
+			ErrRetryBudgetExhausted, e.attempts, elapsed.Round(time.Second), e.err)
+	}
+	return nil
+}
+
+// fail records a failed attempt for ck.
+func (b *retryBudgets) fail(ck ChallengeKey, err error) {
+	now := time.Now()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.entries == nil {
+		b.entries = make(map[ChallengeKey]*challengeBudget)
+	}
+	for k, e := range b.entries {
+		if now.Sub(e.last) > budgetRetention {
+			delete(b.entries, k)
+		}
+	}
+	e, ok := b.entries[ck]
+	if !ok {
+		e = &challengeBudget{first: now}
+		b.entries[ck] = e
+	}
+	e.attempts++
+	e.last = now
+	e.err = err
+}
+
+// reset forgets ck's failures, once it succeeds or is cleaned up.
+func (b *retryBudgets) reset(ck ChallengeKey) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.entries, ck)
+}
@@ -0,0 +1,35 @@
+package solver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryBudget(t *testing.T) {
+	c, server, ch := newTestSolver(t, `{"retry": {"maxAttempts": 1, "challengeAttempts": 2}}`)
+	for i := 0; i < 2; i++ {
+		server.FailNext(errors.New("401 Unauthorized"))
+		if err := c.Present(ch); err == nil || errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("Present %d: expected the Nexus error, got %v", i+1, err)
+		}
+	}
+	calls := len(server.Requests())
+	err := c.Present(ch)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("expected the budget to run out, got %v", err)
+	}
+	if len(server.Requests()) != calls {
+		t.Errorf("expected no Nexus call once the budget ran out")
+	}
+
+	// Cleaning up starts the challenge's budget over.
+	if err = c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if err = c.Present(ch); err != nil {
+		t.Fatalf("Present after CleanUp: %v", err)
+	}
+	if records := server.Records(); len(records) != 1 {
+		t.Errorf("expected one record, got %v", records)
+	}
+}
@@ -0,0 +1,119 @@
+package solver
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// challengeLocks serializes Present and CleanUp calls for the same
+// challenge. cert-manager retries calls that are slow to answer, and
+// without this a retry racing the original would create a second record.
+type challengeLocks = keyedLocks[ChallengeKey]
+
+// presentCalls coalesces overlapping Present calls for the same challenge:
+// the first does the work, and calls that arrive while it runs wait for its
+// result instead of each taking a turn at the challenge lock.
+type presentCalls struct {
+	lock  sync.Mutex
+	calls map[ChallengeKey]*presentCall
+}
+
+type presentCall struct {
+	done chan struct{}
+	err  error
+}
+
+// join returns the Present call in flight for ck, or starts one, in which
+// case leader is true and the caller must finish it.
+func (p *presentCalls) join(ck ChallengeKey) (call *presentCall, leader bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if call, ok := p.calls[ck]; ok {
+		return call, false
+	}
+	if p.calls == nil {
+		p.calls = make(map[ChallengeKey]*presentCall)
+	}
+	call = &presentCall{done: make(chan struct{})}
+	p.calls[ck] = call
+	return call, true
+}
+
+// finish hands err to the calls waiting on call, and lets the next Present
+// for ck start afresh.
+func (p *presentCalls) finish(ck ChallengeKey, call *presentCall, err error) {
+	p.lock.Lock()
+	delete(p.calls, ck)
+	p.lock.Unlock()
+	call.err = err
+	close(call.done)
+}
+
+// wait returns the result of call, or ctx's error if that comes first.
+func (call *presentCall) wait(ctx context.Context) error {
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// zoneLocks serializes record writes to the same Nexus zone, which
+// conflict when they overlap.
+type zoneLocks struct {
+	serialize bool
+	locks     keyedLocks[string]
+}
+
+// acquire locks zone if serialize is set, and returns the func that
+// unlocks it.
+func (l *zoneLocks) acquire(zone string) func() {
+	if !l.serialize {
+		return func() {}
+	}
+	return l.locks.acquire(strings.ToLower(util.UnFqdn(zone)))
+}
+
+// keyedLocks is a set of mutexes created on first use and dropped once
+// nothing holds or waits for them.
+type keyedLocks[K comparable] struct {
+	lock  sync.Mutex
+	locks map[K]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	// waiters counts holders and waiters, so the entry can be dropped
+	// when the last one is done.
+	waiters int
+}
+
+// acquire locks k and returns the func that unlocks it.
+func (l *keyedLocks[K]) acquire(k K) func() {
+	l.lock.Lock()
+	if l.locks == nil {
+		l.locks = make(map[K]*keyedLock)
+	}
+	kl, ok := l.locks[k]
+	if !ok {
+		kl = &keyedLock{}
+		l.locks[k] = kl
+	}
+	kl.waiters++
+	l.lock.Unlock()
+
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.lock.Lock()
+		kl.waiters--
+		if kl.waiters == 0 {
+			delete(l.locks, k)
+		}
+		l.lock.Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package solver
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+func TestZoneLocks(t *testing.T) {
+	l := zoneLocks{serialize: true}
+	release := l.acquire("Example.com.")
+
+	other := make(chan struct{})
+	go func() {
+		l.acquire("example.net")()
+		close(other)
+	}()
+	select {
+	case <-other:
+	case <-time.After(time.Second):
+		t.Fatal("expected a write to another zone not to wait")
+	}
+
+	same := make(chan struct{})
+	go func() {
+		l.acquire("example.com")()
+		close(same)
+	}()
+	select {
+	case <-same:
+		t.Fatal("expected a second write to the same zone to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-same:
+	case <-time.After(time.Second):
+		t.Fatal("expected the waiting write to proceed once the zone was released")
+	}
+
+	l.locks.lock.Lock()
+	defer l.locks.lock.Unlock()
+	if len(l.locks.locks) != 0 {
+		t.Errorf("expected unused locks to be dropped, got %v", l.locks.locks)
+	}
+}
+
+// gatedAPI fails every create with err once release is closed, counting
+// the creates it was asked for.
+type gatedAPI struct {
+	started chan struct{}
+	release chan struct{}
+	creates atomic.Int32
+	err     error
+}
+
+func (g *gatedAPI) CreateChallengeRecord(name, value string) (uuid.UUID, error) {
+	if g.creates.Add(1) == 1 {
+		close(g.started)
+	}
+	<-g.release
+	return uuid.UUID{}, g.err
+}
+
+func (g *gatedAPI) DeleteChallengeRecord(uuid.UUID) error { return nil }
+
+func TestPresentCoalescing(t *testing.T) {
+	c, _, ch := newTestSolver(t, `{"retry": {"maxAttempts": 1}}`)
+	api := &gatedAPI{started: make(chan struct{}), release: make(chan struct{}), err: errors.New("401 Unauthorized")}
+	c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) { return api, nil }
+
+	errs := make([]error, 3)
+	var wg sync.WaitGroup
+	present := func(i int) {
+		defer wg.Done()
+		errs[i] = c.Present(ch)
+	}
+	wg.Add(1)
+	go present(0)
+	<-api.started
+	for i := 1; i < len(errs); i++ {
+		wg.Add(1)
+		go present(i)
+	}
+	// Give the later calls time to join the first before it fails.
+	time.Sleep(100 * time.Millisecond)
+	close(api.release)
+	wg.Wait()
+
+	if n := api.creates.Load(); n != 1 {
+		t.Errorf("expected one create for overlapping Presents, got %d", n)
+	}
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("Present %d: expected the shared failure, got nil", i)
+		}
+	}
+}
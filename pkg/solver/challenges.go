@@ -0,0 +1,71 @@
+package solver
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+)
+
+// challengeIndex indexes Challenges by DNS name and key, which is all a
+// ChallengeRequest has to find its Challenge by.
+const challengeIndex = "dnsNameKey"
+
+func challengeIndexKey(dnsName, key string) string {
+	return dnsName + "/" + key
+}
+
+// challengeLister serves Challenge lookups from an informer cache of every
+// Challenge in the cluster, so finding one doesn't list them all.
+type challengeLister struct {
+	indexer cache.Indexer
+	synced  cache.InformerSynced
+}
+
+func newChallengeLister(cm cmclient.Interface, stopCh <-chan struct{}) (*challengeLister, error) {
+	factory := cminformers.NewSharedInformerFactory(cm, 0)
+	informer := factory.Acme().V1().Challenges().Informer()
+	err := informer.AddIndexers(cache.Indexers{challengeIndex: func(obj interface{}) ([]string, error) {
+		c, ok := obj.(*cmacme.Challenge)
+		if !ok {
+			return nil, nil
+		}
+		return []string{challengeIndexKey(c.Spec.DNSName, c.Spec.Key)}, nil
+	}})
+	if err != nil {
+		return nil, err
+	}
+	factory.Start(stopCh)
+	return &challengeLister{indexer: informer.GetIndexer(), synced: informer.HasSynced}, nil
+}
+
+// find returns the Challenge for dnsName and key, or nil if there is none.
+func (l *challengeLister) find(ctx context.Context, dnsName, key string) (*cmacme.Challenge, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), l.synced) {
+		return nil, errors.New("timed out waiting for the challenge cache to sync")
+	}
+	objs, err := l.indexer.ByIndex(challengeIndex, challengeIndexKey(dnsName, key))
+	if err != nil || len(objs) == 0 {
+		return nil, err
+	}
+	return objs[0].(*cmacme.Challenge), nil
+}
+
+// challengeLister returns the process's Challenge lister, starting it the
+// first time it's needed.
+func (p *Process) challengeLister(kubeClientConfig *rest.Config, stopCh <-chan struct{}) (*challengeLister, error) {
+	p.challengesOnce.Do(func() {
+		cm, err := cmclient.NewForConfig(kubeClientConfig)
+		if err != nil {
+			p.challengesErr = err
+			return
+		}
+		p.challenges, p.challengesErr = newChallengeLister(cm, stopCh)
+	})
+	return p.challenges, p.challengesErr
+}
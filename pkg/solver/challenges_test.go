@@ -0,0 +1,53 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+)
+
+func TestChallengeLister(t *testing.T) {
+	cm := cmfake.NewSimpleClientset(&cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "team-a"},
+		Spec:       cmacme.ChallengeSpec{DNSName: "www.example.com", Key: "token"},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	challenges, err := newChallengeLister(cm, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if c, err := challenges.find(ctx, "www.example.com", "token"); err != nil || c == nil || c.Name != "www" {
+		t.Errorf("expected the matching challenge, got %v, %v", c, err)
+	}
+	for _, miss := range [][2]string{{"www.example.com", "other"}, {"api.example.com", "token"}} {
+		if c, err := challenges.find(ctx, miss[0], miss[1]); err != nil || c != nil {
+			t.Errorf("find(%q, %q): expected no challenge, got %v, %v", miss[0], miss[1], c, err)
+		}
+	}
+}
+
+// waitForChallenge waits for the lister's cache to hold a Challenge for
+// dnsName and key that satisfies ok, as informers see writes eventually.
+func waitForChallenge(t *testing.T, l *challengeLister, dnsName, key string, ok func(*cmacme.Challenge) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := l.find(context.Background(), dnsName, key)
+		if err == nil && c != nil && ok(c) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for challenge %s/%s, last saw %v, %v", dnsName, key, c, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
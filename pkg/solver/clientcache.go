@@ -0,0 +1,101 @@
+package solver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+type clientCacheKey struct {
+	apiVersion string
+	domain     string
+	service    string
+	keyHash    [sha256.Size]byte
+}
+
+type cachedClient struct {
+	client  nexusclient.API
+	expires time.Time
+}
+
+// clientCache reuses Nexus clients across challenges. Entries are keyed by
+// a hash of the API key so a rotated key gets a fresh client.
+type clientCache struct {
+	ttl time.Duration
+
+	lock    sync.Mutex
+	entries map[clientCacheKey]cachedClient
+}
+
+// get returns a cached client for (apiVersion, domain, service, key),
+// calling build to create one if there is no live entry.
+func (cc *clientCache) get(apiVersion, domain, service string, key []byte, build func() (nexusclient.API, error)) (nexusclient.API, error) {
+	if cc.ttl <= 0 {
+		return build()
+	}
+
+	ck := clientCacheKey{apiVersion: apiVersion, domain: domain, service: service, keyHash: sha256.Sum256(key)}
+	now := time.Now()
+
+	cc.lock.Lock()
+	entry, ok := cc.entries[ck]
+	cc.lock.Unlock()
+	if ok && now.Before(entry.expires) {
+		clientCacheLookupsTotal.WithLabelValues("hit").Inc()
+		return entry.client, nil
+	}
+	clientCacheLookupsTotal.WithLabelValues("miss").Inc()
+
+	client, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	if cc.entries == nil {
+		cc.entries = make(map[clientCacheKey]cachedClient)
+	}
+	for k, e := range cc.entries {
+		if !now.Before(e.expires) {
+			delete(cc.entries, k)
+		}
+	}
+	cc.entries[ck] = cachedClient{client: client, expires: now.Add(cc.ttl)}
+	return client, nil
+}
+
+// flush drops every cached client.
+func (cc *clientCache) flush() {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	clear(cc.entries)
+}
+
+// forget drops cached clients built from any of the given raw Secret
+// values, so a rotated or revoked key stops being used before the TTL
+// runs out. Each value is matched under every encoding decodeKey accepts.
+func (cc *clientCache) forget(values [][]byte) (evicted int) {
+	hashes := map[[sha256.Size]byte]bool{}
+	for _, v := range values {
+		trimmed := strings.TrimSpace(string(v))
+		hashes[sha256.Sum256([]byte(trimmed))] = true
+		if key, err := base64.StdEncoding.DecodeString(trimmed); err == nil {
+			hashes[sha256.Sum256(key)] = true
+		}
+	}
+
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	for k := range cc.entries {
+		if hashes[k.keyHash] {
+			delete(cc.entries, k)
+			evicted++
+		}
+	}
+	return
+}
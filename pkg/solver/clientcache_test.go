@@ -0,0 +1,59 @@
+package solver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+func TestClientCache(t *testing.T) {
+	cc := &clientCache{ttl: time.Hour}
+	builds := 0
+	build := func() (nexusclient.API, error) {
+		builds++
+		return nil, nil
+	}
+
+	cc.get("v1", "example.com", "svc", []byte("key"), build)
+	cc.get("v1", "example.com", "svc", []byte("key"), build)
+	if builds != 1 {
+		t.Errorf("expected cached client to be reused, built %d times", builds)
+	}
+
+	cc.get("v1", "example.com", "svc", []byte("rotated"), build)
+	cc.get("v1", "example.org", "svc", []byte("key"), build)
+	if builds != 3 {
+		t.Errorf("expected a new client per key and domain, built %d times", builds)
+	}
+
+	for k, e := range cc.entries {
+		e.expires = time.Now().Add(-time.Second)
+		cc.entries[k] = e
+	}
+	cc.get("v1", "example.com", "svc", []byte("key"), build)
+	if builds != 4 {
+		t.Errorf("expected expired client to be rebuilt, built %d times", builds)
+	}
+	if len(cc.entries) != 1 {
+		t.Errorf("expected expired entries to be pruned, have %d", len(cc.entries))
+	}
+}
+
+func TestClientCacheForget(t *testing.T) {
+	cc := &clientCache{ttl: time.Hour}
+	build := func() (nexusclient.API, error) { return nil, nil }
+
+	cc.get("v1", "example.com", "svc", []byte("secret"), build)
+	cc.get("v1", "example.com", "svc", []byte("other"), build)
+
+	if n := cc.forget([][]byte{[]byte("c2VjcmV0\n")}); n != 1 {
+		t.Errorf("expected base64 value to evict one client, evicted %d", n)
+	}
+	if n := cc.forget([][]byte{[]byte("other")}); n != 1 {
+		t.Errorf("expected plain value to evict one client, evicted %d", n)
+	}
+	if len(cc.entries) != 0 {
+		t.Errorf("expected no cached clients left, have %d", len(cc.entries))
+	}
+}
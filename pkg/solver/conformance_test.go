@@ -0,0 +1,39 @@
+//go:build conformance
+
+package solver
+
+import (
+	"os"
+	"testing"
+
+	acmetest "github.com/cert-manager/cert-manager/test/acme"
+)
+
+var (
+	zone      = os.Getenv("TEST_ZONE_NAME")
+	dnsServer = os.Getenv("TEST_DNS_SERVER")
+)
+
+// TestRunsSuite runs cert-manager's DNS01 conformance tests against a real
+// Nexus zone. testdata/nexus/config.json is passed as the solver config, and
+// any other manifests there (such as the API key Secret it refers to) are
+// applied to the test namespace. cert-manager's test harness panics at init
+// without the control plane binaries, so the suite is behind the
+// conformance build tag; `make test` fetches the binaries and sets it.
+func TestRunsSuite(t *testing.T) {
+	if zone == "" {
+		t.Skip("TEST_ZONE_NAME not set; skipping DNS01 conformance tests")
+	}
+
+	opts := []acmetest.Option{
+		acmetest.SetResolvedZone(zone),
+		acmetest.SetAllowAmbientCredentials(false),
+		acmetest.SetManifestPath("testdata/nexus"),
+	}
+	if dnsServer != "" {
+		opts = append(opts, acmetest.SetDNSServer(dnsServer))
+	}
+	fixture := acmetest.NewFixture(New(), opts...)
+
+	fixture.RunConformance(t)
+}
@@ -0,0 +1,207 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// nexusChallengeResource is the NexusChallenge CRD the chart installs.
+var nexusChallengeResource = schema.GroupVersionResource{
+	Group:    "nexus.fudo.org",
+	Version:  "v1alpha1",
+	Resource: "nexuschallenges",
+}
+
+const nexusChallengeKind = "NexusChallenge"
+
+// crdStoreVerbs are the verbs crdStore uses on NexusChallenges.
+var crdStoreVerbs = []string{"get", "list", "create", "update", "delete"}
+
+// nexusChallenge is a NexusChallenge resource: one presented challenge
+// record, visible with kubectl get nexuschallenges.
+type nexusChallenge struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   nexusChallengeSpec   `json:"spec"`
+	Status nexusChallengeStatus `json:"status,omitempty"`
+}
+
+type nexusChallengeSpec struct {
+	FQDN        string                     `json:"fqdn"`
+	Zone        string                     `json:"zone,omitempty"`
+	RecordID    uuid.UUID                  `json:"recordID"`
+	Backend     string                     `json:"backend,omitempty"`
+	Solver      string                     `json:"solver,omitempty"`
+	PresentedAt metav1.Time                `json:"presentedAt,omitempty"`
+	Request     *v1alpha1.ChallengeRequest `json:"request,omitempty"`
+}
+
+type nexusChallengeStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// conditionPresented is true once the record has been created in Nexus.
+const conditionPresented = "Presented"
+
+// crdStore keeps each challenge as a NexusChallenge in namespace. Unlike a
+// ConfigMap, every challenge is its own object, so replicas never contend
+// for writes and the state can be inspected with kubectl.
+type crdStore struct {
+	client    dynamic.Interface
+	namespace string
+}
+
+func (s *crdStore) resource() dynamic.ResourceInterface {
+	return s.client.Resource(nexusChallengeResource).Namespace(s.namespace)
+}
+
+func (s *crdStore) Get(ctx context.Context, ck ChallengeKey) (sc StoredChallenge, ok bool, err error) {
+	obj, err := s.resource().Get(ctx, ck.StoreKey(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	sc, err = fromNexusChallenge(obj)
+	ok = err == nil
+	return
+}
+
+// List returns every stored challenge, ordered by when it was presented.
+// Unreadable objects are skipped.
+func (s *crdStore) List(ctx context.Context) ([]StoredChallenge, error) {
+	objs, err := s.resource().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]StoredChallenge, 0, len(objs.Items))
+	for i := range objs.Items {
+		if sc, err := fromNexusChallenge(&objs.Items[i]); err == nil {
+			stored = append(stored, sc)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].PresentedAt.Before(stored[j].PresentedAt) })
+	return stored, nil
+}
+
+// Claim creates the NexusChallenge for ck unless one exists, and returns
+// the challenge the store ends up with.
+func (s *crdStore) Claim(ctx context.Context, ck ChallengeKey, sc StoredChallenge) (held StoredChallenge, err error) {
+	obj, err := toNexusChallenge(ck, sc)
+	if err != nil {
+		return
+	}
+	_, err = s.resource().Create(ctx, obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		existing, getErr := s.resource().Get(ctx, ck.StoreKey(), metav1.GetOptions{})
+		if getErr != nil {
+			return held, getErr
+		}
+		return fromNexusChallenge(existing)
+	}
+	return sc, err
+}
+
+// Put creates or replaces the NexusChallenge for ck.
+func (s *crdStore) Put(ctx context.Context, ck ChallengeKey, sc StoredChallenge) error {
+	obj, err := toNexusChallenge(ck, sc)
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := s.resource().Get(ctx, ck.StoreKey(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = s.resource().Create(ctx, obj, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(nexusChallengeResource.GroupResource(), ck.StoreKey(), err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		_, err = s.resource().Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *crdStore) Delete(ctx context.Context, ck ChallengeKey) error {
+	err := s.resource().Delete(ctx, ck.StoreKey(), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func toNexusChallenge(ck ChallengeKey, sc StoredChallenge) (*unstructured.Unstructured, error) {
+	presentedAt := metav1.NewTime(sc.PresentedAt)
+	nc := nexusChallenge{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: nexusChallengeResource.GroupVersion().String(),
+			Kind:       nexusChallengeKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ck.StoreKey(),
+			Labels: map[string]string{"nexus.fudo.org/solver": sc.solver()},
+		},
+		Spec: nexusChallengeSpec{
+			FQDN:        ck.fqdn,
+			Zone:        sc.Zone,
+			RecordID:    sc.ID,
+			Backend:     sc.Backend,
+			Solver:      sc.Solver,
+			PresentedAt: presentedAt,
+			Request:     sc.Request,
+		},
+		Status: nexusChallengeStatus{Conditions: []metav1.Condition{{
+			Type:               conditionPresented,
+			Status:             metav1.ConditionTrue,
+			Reason:             "RecordCreated",
+			Message:            "TXT record " + sc.ID.String() + " created in Nexus",
+			LastTransitionTime: presentedAt,
+		}}},
+	}
+	raw, err := json.Marshal(nc)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	err = obj.UnmarshalJSON(raw)
+	return obj, err
+}
+
+func fromNexusChallenge(obj *unstructured.Unstructured) (sc StoredChallenge, err error) {
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return
+	}
+	var nc nexusChallenge
+	if err = json.Unmarshal(raw, &nc); err != nil {
+		return
+	}
+	sc = StoredChallenge{
+		ID:          nc.Spec.RecordID,
+		PresentedAt: nc.Spec.PresentedAt.Time,
+		Request:     nc.Spec.Request,
+		Solver:      nc.Spec.Solver,
+		Zone:        nc.Spec.Zone,
+		Backend:     nc.Spec.Backend,
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestCRDStore(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{nexusChallengeResource: nexusChallengeKind + "List"})
+	store := &crdStore{client: client, namespace: "cert-manager"}
+	ctx := context.Background()
+	ck := ChallengeKey{fqdn: "_acme-challenge.example.com.", key: "token"}
+
+	if _, ok, err := store.Get(ctx, ck); err != nil || ok {
+		t.Fatalf("expected no stored challenge, got ok=%v err=%v", ok, err)
+	}
+
+	id := uuid.New()
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: ck.fqdn, Key: ck.key}
+	sc := StoredChallenge{ID: id, PresentedAt: time.Now().Truncate(time.Second), Request: ch, Solver: "nexus", Zone: "example.com"}
+	if held, err := store.Claim(ctx, ck, sc); err != nil || held.ID != id {
+		t.Fatalf("claim: %+v, %v", held, err)
+	}
+	if held, err := store.Claim(ctx, ck, StoredChallenge{ID: uuid.New()}); err != nil || held.ID != id {
+		t.Errorf("expected claim to return the existing entry %s, got %+v, %v", id, held, err)
+	}
+	if _, err := store.Claim(ctx, ChallengeKey{fqdn: ck.fqdn, key: "other"}, StoredChallenge{ID: uuid.New()}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	got, ok, err := store.Get(ctx, ck)
+	if err != nil || !ok {
+		t.Fatalf("expected stored challenge, got ok=%v err=%v", ok, err)
+	}
+	if got.ID != id || got.Zone != "example.com" || !got.PresentedAt.Equal(sc.PresentedAt) || got.Request == nil || got.Request.Key != ck.key {
+		t.Errorf("expected %+v, got %+v", sc, got)
+	}
+
+	obj, err := client.Resource(nexusChallengeResource).Namespace("cert-manager").Get(ctx, ck.StoreKey(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get NexusChallenge: %v", err)
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if len(conditions) != 1 || conditions[0].(map[string]interface{})["type"] != conditionPresented {
+		t.Errorf("expected a Presented condition, got %v", conditions)
+	}
+
+	stored, err := store.List(ctx)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("expected two stored challenges, got %v, %v", stored, err)
+	}
+
+	if err := store.Delete(ctx, ck); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.Delete(ctx, ck); err != nil {
+		t.Errorf("expected deleting a missing challenge to succeed, got %v", err)
+	}
+	if _, ok, err := store.Get(ctx, ck); err != nil || ok {
+		t.Fatalf("expected challenge to be deleted, got ok=%v err=%v", ok, err)
+	}
+}
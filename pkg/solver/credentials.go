@@ -0,0 +1,290 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// CredentialProvider supplies the Nexus API key for a challenge. Providers
+// register themselves by name and are picked by credentialSource.provider.
+type CredentialProvider interface {
+	APIKey(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config) (string, error)
+	// Ambient reports whether the key belongs to the webhook rather than
+	// the issuer's namespace, which issuers must opt in to.
+	Ambient() bool
+}
+
+// credentialSource selects where the Nexus API key comes from.
+type credentialSource struct {
+	// Provider names a registered CredentialProvider. It may be left
+	// empty when a provider-specific block such as vault is given.
+	Provider string       `json:"provider,omitempty"`
+	Vault    *vaultConfig `json:"vault,omitempty"`
+}
+
+// zoneCredentials is the service and API key to use for Zone and its
+// subdomains.
+type zoneCredentials struct {
+	Zone            string       `json:"zone"`
+	Service         string       `json:"service,omitempty"`
+	ApiKeySecretRef secretKeyRef `json:"apikeysecret"`
+}
+
+const (
+	providerKubernetesSecret = "kubernetes-secret"
+	providerFile             = "file"
+	providerEnv              = "env"
+	providerExternal         = "external"
+	providerVault            = "vault"
+)
+
+var credentialProviders = map[string]func(*Solver) CredentialProvider{}
+
+// registerCredentialProvider makes a provider available to solver configs.
+// newProvider is called once, when the solver is created.
+func registerCredentialProvider(name string, newProvider func(*Solver) CredentialProvider) {
+	if _, ok := credentialProviders[name]; ok {
+		panic(fmt.Sprintf("credential provider %q registered twice", name))
+	}
+	credentialProviders[name] = newProvider
+}
+
+func init() {
+	registerCredentialProvider(providerKubernetesSecret, func(c *Solver) CredentialProvider {
+		return &secretProvider{c}
+	})
+}
+
+func (c *Solver) initCredentialProviders() {
+	c.credentials = make(map[string]CredentialProvider, len(credentialProviders))
+	for name, newProvider := range credentialProviders {
+		c.credentials[name] = newProvider(c)
+	}
+}
+
+// applyZoneCredentials replaces the service and secret with those of the
+// most specific entry in Zones covering domain, if any.
+func (cfg *Config) applyZoneCredentials(domain string) {
+	domain = strings.ToLower(util.UnFqdn(domain))
+	var best *zoneCredentials
+	for i := range cfg.Zones {
+		z := &cfg.Zones[i]
+		zone := strings.ToLower(util.UnFqdn(z.Zone))
+		if domain != zone && !strings.HasSuffix(domain, "."+zone) {
+			continue
+		}
+		if best == nil || len(zone) > len(util.UnFqdn(best.Zone)) {
+			best = z
+		}
+	}
+	if best == nil {
+		return
+	}
+	cfg.ApiKeySecretRef = best.ApiKeySecretRef
+	if best.Service != "" {
+		cfg.Service = best.Service
+	}
+}
+
+// zonesSetService reports whether every entry in Zones names its service,
+// so no top-level service is needed.
+func (cfg *Config) zonesSetService() bool {
+	for _, z := range cfg.Zones {
+		if z.Service == "" {
+			return false
+		}
+	}
+	return len(cfg.Zones) > 0
+}
+
+// providerName picks the configured provider, falling back to whichever the
+// rest of the config implies: the file provider if the webhook has an
+// apiKeyFile, and the environment otherwise.
+func (cfg *Config) providerName(apiKeyFile string) string {
+	switch {
+	case cfg.CredentialSource.Provider != "":
+		return cfg.CredentialSource.Provider
+	case cfg.CredentialSource.Vault != nil:
+		return providerVault
+	case cfg.ApiKeySecretRef.set():
+		return providerKubernetesSecret
+	case apiKeyFile != "":
+		return providerFile
+	default:
+		return providerEnv
+	}
+}
+
+// apiKey returns the Nexus API key for a challenge from its configured
+// credential provider.
+func (c *Solver) apiKey(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config) (string, error) {
+	name := cfg.providerName(c.proc.settings.APIKeyFile)
+	provider, ok := c.credentials[name]
+	if !ok {
+		return "", fmt.Errorf("unknown credential provider %q", name)
+	}
+	if provider.Ambient() && !ch.AllowAmbientCredentials {
+		if cfg.CredentialSource.Provider == "" {
+			return "", errors.New("no apikeysecret provided in config, and ambient credentials are not allowed for this issuer")
+		}
+		return "", fmt.Errorf("credential provider %q uses ambient credentials, which are not allowed for this issuer", name)
+	}
+	return provider.APIKey(ctx, ch, cfg)
+}
+
+// secretKeyRef selects the key in a Secret holding a Nexus API key. The
+// Secret is in the issuer's namespace unless Namespace names one of
+// --allowed-secret-namespaces.
+type secretKeyRef struct {
+	corev1.SecretKeySelector `json:",inline"`
+	Namespace                string `json:"namespace,omitempty"`
+	// Selector, instead of a name, picks the newest Secret with these
+	// labels that holds Key, so rotation tooling can create a new Secret
+	// without editing every Issuer.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// set reports whether ref names or selects a Secret.
+func (ref secretKeyRef) set() bool {
+	return ref.Name != "" || ref.Selector != nil
+}
+
+// secretNamespace returns the namespace to read ref from for an issuer in
+// issuerNamespace, refusing namespaces other than those in allowed.
+func secretNamespace(ref secretKeyRef, issuerNamespace string, allowed []string) (string, error) {
+	if ref.Namespace == "" || ref.Namespace == issuerNamespace {
+		return issuerNamespace, nil
+	}
+	for _, namespace := range allowed {
+		if namespace == ref.Namespace {
+			return ref.Namespace, nil
+		}
+	}
+	return "", fmt.Errorf("apikeysecret namespace %q is not in --allowed-secret-namespaces", ref.Namespace)
+}
+
+// secretProvider reads the key from apikeysecret, in the issuer's
+// namespace by default.
+type secretProvider struct {
+	c *Solver
+}
+
+func (p *secretProvider) Ambient() bool { return false }
+
+func (p *secretProvider) APIKey(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config) (key string, err error) {
+	ref := cfg.ApiKeySecretRef
+	if !ref.set() {
+		err = errors.New("secret name not provided")
+		return
+	}
+	namespace, err := secretNamespace(ref, ch.ResourceNamespace, p.c.proc.settings.AllowedSecretNamespaces)
+	if err != nil {
+		return
+	}
+	if p.c.client == nil {
+		err = errors.New("apikeysecret can't be read without a Kubernetes client; use an ambient key instead")
+		return
+	}
+
+	ctx, cancel := p.c.proc.withKubeTimeout(ctx)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "GetSecret", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("k8s.secret.name", ref.Name),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if ref.Selector != nil {
+		return p.selectKey(ctx, namespace, ref)
+	}
+
+	var keyValue *corev1.Secret
+	if p.c.secrets != nil {
+		keyValue, err = p.c.secrets.get(ctx, namespace, ref.Name)
+	} else {
+		keyValue, err = p.c.client.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		err = fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	data, ok := keyValue.Data[ref.Key]
+	if !ok {
+		err = fmt.Errorf("%w: secret %s/%s has no key %q", ErrSecretNotFound, namespace, ref.Name, ref.Key)
+		return
+	}
+	key = string(data)
+	return
+}
+
+// selectKey reads the key from the newest Secret in namespace matching
+// ref.Selector that holds ref.Key.
+func (p *secretProvider) selectKey(ctx context.Context, namespace string, ref secretKeyRef) (key string, err error) {
+	selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+	if err != nil {
+		err = fmt.Errorf("invalid apikeysecret.selector: %w", err)
+		return
+	}
+
+	var secrets []*corev1.Secret
+	if p.c.secrets != nil {
+		secrets, err = p.c.secrets.list(ctx, namespace, selector)
+	} else {
+		var list *corev1.SecretList
+		list, err = p.c.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err == nil {
+			for i := range list.Items {
+				secrets = append(secrets, &list.Items[i])
+			}
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	var newest *corev1.Secret
+	for _, s := range secrets {
+		if _, ok := s.Data[ref.Key]; !ok {
+			continue
+		}
+		if newest == nil || newest.CreationTimestamp.Before(&s.CreationTimestamp) ||
+			(newest.CreationTimestamp.Equal(&s.CreationTimestamp) && s.Name > newest.Name) {
+			newest = s
+		}
+	}
+	if newest == nil {
+		err = fmt.Errorf("%w: no secret in %s matching %s has key %q", ErrSecretNotFound, namespace, selector, ref.Key)
+		return
+	}
+	key = string(newest.Data[ref.Key])
+	return
+}
+
+// secretRotated evicts cached Nexus clients built from a Secret's previous
+// contents once the informer sees it change or go away.
+func (c *Solver) secretRotated(old *corev1.Secret) {
+	values := make([][]byte, 0, len(old.Data))
+	for _, v := range old.Data {
+		values = append(values, v)
+	}
+	if n := c.clients.forget(values); n > 0 {
+		c.logger().Info("secret changed, dropped cached Nexus clients", "namespace", old.Namespace, "secret", old.Name, "clients", n)
+	}
+}
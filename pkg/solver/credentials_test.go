@@ -0,0 +1,188 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("from-secret")},
+	})
+	c := New(WithClient(client))
+	ctx := context.Background()
+
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default"}
+	cfg := &Config{ApiKeySecretRef: secretKeyRef{SecretKeySelector: corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "nexus"},
+		Key:                  "key",
+	}}}
+	if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "from-secret" {
+		t.Errorf("expected key from secret, got %q, %v", key, err)
+	}
+
+	missing := *cfg
+	missing.ApiKeySecretRef.Key = "other"
+	if _, err := c.apiKey(ctx, ch, &missing); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound for a missing key, got %v", err)
+	}
+	missing.ApiKeySecretRef.Name = "absent"
+	if _, err := c.apiKey(ctx, ch, &missing); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound for a missing secret, got %v", err)
+	}
+
+	os.Setenv("NEXUS_API_KEY", "from-env")
+	defer os.Unsetenv("NEXUS_API_KEY")
+	cfg = &Config{}
+	if _, err := c.apiKey(ctx, ch, cfg); err == nil {
+		t.Errorf("expected ambient credentials to be refused")
+	}
+	ch.AllowAmbientCredentials = true
+	if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "from-env" {
+		t.Errorf("expected key from environment, got %q, %v", key, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c.proc.settings.APIKeyFile = path
+	if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "from-file\n" {
+		t.Errorf("expected key file to take precedence, got %q, %v", key, err)
+	}
+
+	c.proc.settings.CredentialCommand = "echo from-$NEXUS_NAMESPACE"
+	cfg.CredentialSource.Provider = providerExternal
+	if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "from-default\n" {
+		t.Errorf("expected key from credential command, got %q, %v", key, err)
+	}
+
+	cfg.CredentialSource.Provider = "unknown"
+	if _, err := c.apiKey(ctx, ch, cfg); err == nil {
+		t.Errorf("expected an error for an unknown provider")
+	}
+}
+
+func TestCrossNamespaceSecret(t *testing.T) {
+	c := New(WithClient(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus", Namespace: "shared"},
+		Data:       map[string][]byte{"key": []byte("shared-key")},
+	})))
+	ctx := context.Background()
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "team-a"}
+	cfg := &Config{ApiKeySecretRef: secretKeyRef{
+		SecretKeySelector: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "nexus"}, Key: "key"},
+		Namespace:         "shared",
+	}}
+
+	if _, err := c.apiKey(ctx, ch, cfg); err == nil {
+		t.Errorf("expected a namespace outside --allowed-secret-namespaces to be refused")
+	}
+	c.proc.settings.AllowedSecretNamespaces = []string{"other", "shared"}
+	if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "shared-key" {
+		t.Errorf("expected key from the shared namespace, got %q, %v", key, err)
+	}
+}
+
+func TestApplyZoneCredentials(t *testing.T) {
+	secret := func(name string) secretKeyRef {
+		return secretKeyRef{SecretKeySelector: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "key"}}
+	}
+	base := Config{
+		Service:         "default",
+		ApiKeySecretRef: secret("default"),
+		Zones: []zoneCredentials{
+			{Zone: "example.com", ApiKeySecretRef: secret("example")},
+			{Zone: "internal.example.com.", Service: "internal", ApiKeySecretRef: secret("internal")},
+		},
+	}
+	tests := []struct {
+		domain, service, secret string
+	}{
+		{"example.org", "default", "default"},
+		{"example.com", "default", "example"},
+		{"www.example.com", "default", "example"},
+		{"dev.internal.example.com", "internal", "internal"},
+		{"notexample.com", "default", "default"},
+	}
+	for _, test := range tests {
+		cfg := base
+		cfg.applyZoneCredentials(test.domain)
+		if cfg.Service != test.service || cfg.ApiKeySecretRef.Name != test.secret {
+			t.Errorf("applyZoneCredentials(%q) selected %s/%s, expected %s/%s",
+				test.domain, cfg.Service, cfg.ApiKeySecretRef.Name, test.service, test.secret)
+		}
+	}
+}
+
+type staticProvider string
+
+func (staticProvider) Ambient() bool { return false }
+
+func (p staticProvider) APIKey(context.Context, *v1alpha1.ChallengeRequest, *Config) (string, error) {
+	return string(p), nil
+}
+
+func TestWithCredentialProvider(t *testing.T) {
+	c := New(WithCredentialProvider("static", staticProvider("from-option")))
+	cfg := &Config{CredentialSource: credentialSource{Provider: "static"}}
+	key, err := c.apiKey(context.Background(), &v1alpha1.ChallengeRequest{}, cfg)
+	if err != nil || key != "from-option" {
+		t.Errorf("expected key from the custom provider, got %q, %v", key, err)
+	}
+}
+
+func TestSecretSelector(t *testing.T) {
+	labels := map[string]string{"nexus.fudo.org/zone": "example.com"}
+	secret := func(name string, age time.Duration, labels map[string]string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "default", Labels: labels,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age).Truncate(time.Second)),
+			},
+			Data: data,
+		}
+	}
+	client := fake.NewSimpleClientset(
+		secret("nexus-old", 2*time.Hour, labels, map[string][]byte{"key": []byte("old-key")}),
+		secret("nexus-new", time.Hour, labels, map[string][]byte{"key": []byte("new-key")}),
+		secret("nexus-empty", time.Minute, labels, map[string][]byte{"other": []byte("x")}),
+		secret("unlabeled", 0, nil, map[string][]byte{"key": []byte("wrong-key")}),
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "default"}
+	cfg := &Config{ApiKeySecretRef: secretKeyRef{
+		SecretKeySelector: corev1.SecretKeySelector{Key: "key"},
+		Selector:          &metav1.LabelSelector{MatchLabels: labels},
+	}}
+	for _, informer := range []bool{false, true} {
+		c := New(WithClient(client))
+		if informer {
+			c.secrets = newSecretLister(client, stopCh)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if key, err := c.apiKey(ctx, ch, cfg); err != nil || key != "new-key" {
+			t.Errorf("informer=%v: expected the newest matching secret's key, got %q, %v", informer, key, err)
+		}
+		missing := *cfg
+		missing.ApiKeySecretRef.Key = "absent"
+		if _, err := c.apiKey(ctx, ch, &missing); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("informer=%v: expected ErrSecretNotFound when no match holds the key, got %v", informer, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,137 @@
+package solver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	statePresenting = "presenting"
+	statePresented  = "presented"
+	stateCleaningUp = "cleaning up"
+)
+
+// debugChallenge is one entry of the /debug/challenges listing.
+type debugChallenge struct {
+	Solver string `json:"solver"`
+	FQDN   string `json:"fqdn"`
+	Zone   string `json:"zone,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Age    string `json:"age,omitempty"`
+	State  string `json:"state"`
+}
+
+// markActive records that a Present or CleanUp is working on ck and
+// returns the func that clears it.
+func (c *Solver) markActive(ck ChallengeKey, state string) func() {
+	c.lock.Lock()
+	if c.active == nil {
+		c.active = make(map[ChallengeKey]string)
+	}
+	c.active[ck] = state
+	c.lock.Unlock()
+	return func() {
+		c.lock.Lock()
+		delete(c.active, ck)
+		c.lock.Unlock()
+	}
+}
+
+// debugChallenges lists the challenges this replica tracks or is working
+// on. With a store, other replicas' challenges aren't included.
+func (c *Solver) debugChallenges(now time.Time) (list []debugChallenge) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for ck, tc := range c.challenges {
+		d := debugChallenge{Solver: c.Name(), FQDN: ck.fqdn, Zone: tc.zone, ID: tc.id.String(), State: statePresented}
+		if !tc.presentedAt.IsZero() {
+			d.Age = now.Sub(tc.presentedAt).Round(time.Second).String()
+		}
+		if state, ok := c.active[ck]; ok {
+			d.State = state
+		}
+		list = append(list, d)
+	}
+	for ck, state := range c.active {
+		if _, ok := c.challenges[ck]; !ok {
+			list = append(list, debugChallenge{Solver: c.Name(), FQDN: ck.fqdn, State: state})
+		}
+	}
+	return
+}
+
+func (p *Process) serveDebugChallenges(w http.ResponseWriter, r *http.Request) {
+	solvers := p.initializedSolvers()
+
+	now := time.Now()
+	list := []debugChallenge{}
+	for _, c := range solvers {
+		list = append(list, c.debugChallenges(now)...)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].FQDN != list[j].FQDN {
+			return list[i].FQDN < list[j].FQDN
+		}
+		return list[i].Solver < list[j].Solver
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// readDebugToken reads the bearer token from path.
+func readDebugToken(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("--debug-bind-address requires --debug-token-file")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read debug token file: %w", err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", fmt.Errorf("debug token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// requireToken rejects requests that don't carry token as a bearer token,
+// since the listing names zones and record IDs, and debug logging would
+// log more.
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startDebugServer serves /debug/challenges and /debug/flags/v on addr
+// until stopCh is closed.
+func (p *Process) startDebugServer(addr, token string, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/challenges", requireToken(token, http.HandlerFunc(p.serveDebugChallenges)))
+	mux.Handle("/debug/flags/v", requireToken(token, http.HandlerFunc(p.serveLogLevel)))
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-stopCh
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			p.log.Error(err, "debug server failed", "address", addr)
+		}
+	}()
+}
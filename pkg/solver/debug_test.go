@@ -0,0 +1,52 @@
+package solver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDebugChallenges(t *testing.T) {
+	id := uuid.New()
+	presented := ChallengeKey{fqdn: "_acme-challenge.a.example.com.", key: "one"}
+	pending := ChallengeKey{fqdn: "_acme-challenge.b.example.com.", key: "two"}
+	c := New()
+	c.challenges = map[ChallengeKey]trackedChallenge{
+		presented: {id: id, zone: "example.com", presentedAt: time.Now().Add(-time.Minute)},
+	}
+	defer c.markActive(pending, statePresenting)()
+	c.proc.register(c)
+
+	handler := requireToken("s3cret", http.HandlerFunc(c.proc.serveDebugChallenges))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/challenges", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a request without a token to be refused, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/challenges", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var list []debugChallenge
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid listing: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected two challenges, got %+v", list)
+	}
+	if d := list[0]; d.FQDN != presented.fqdn || d.ID != id.String() || d.Zone != "example.com" || d.State != statePresented || d.Age != "1m0s" {
+		t.Errorf("unexpected presented challenge %+v", d)
+	}
+	if d := list[1]; d.FQDN != pending.fqdn || d.ID != "" || d.State != statePresenting {
+		t.Errorf("unexpected pending challenge %+v", d)
+	}
+}
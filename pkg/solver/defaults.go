@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const defaultsKey = "defaults.json"
+
+// clusterDefaults holds the config fields from the DefaultsConfigMap,
+// shared by every solver in the process.
+type clusterDefaults struct {
+	log    logr.Logger
+	lock   sync.RWMutex
+	fields map[string]json.RawMessage
+}
+
+// watch keeps the defaults in step with the ConfigMap, and returns once
+// its first version has been read.
+func (d *clusterDefaults) watch(client kubernetes.Interface, namespace, name string, stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    d.update,
+		UpdateFunc: func(_, obj interface{}) { d.update(obj) },
+		DeleteFunc: func(interface{}) {
+			d.log.Info("cluster defaults ConfigMap deleted, clearing defaults", "namespace", namespace, "name", name)
+			_ = d.load("")
+		},
+	})
+	factory.Start(stopCh)
+	cache.WaitForCacheSync(stopCh, informer.HasSynced)
+}
+
+func (d *clusterDefaults) update(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	if err := d.load(cm.Data[defaultsKey]); err != nil {
+		// Keep what we had: one bad edit shouldn't break every issuer.
+		d.log.Error(err, "ignoring invalid cluster defaults", "namespace", cm.Namespace, "name", cm.Name)
+	}
+}
+
+// load replaces the defaults with raw, a JSON object of config fields,
+// unless it isn't a valid config. Empty raw clears them.
+func (d *clusterDefaults) load(raw string) error {
+	var fields map[string]json.RawMessage
+	if strings.TrimSpace(raw) != "" {
+		if _, err := loadConfig(&extapi.JSON{Raw: []byte(raw)}); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return err
+		}
+	}
+	d.lock.Lock()
+	d.fields = fields
+	d.lock.Unlock()
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	d.log.Info("loaded cluster defaults", "fields", names)
+	return nil
+}
+
+// apply adds the defaults to fields.
+func (d *clusterDefaults) apply(fields map[string]json.RawMessage) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for name, value := range d.fields {
+		fields[name] = value
+	}
+}
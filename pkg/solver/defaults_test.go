@@ -0,0 +1,64 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterDefaults(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus-defaults", Namespace: "cert-manager"},
+		Data:       map[string]string{defaultsKey: `{"service": "cluster", "zoneName": "example.com"}`},
+	}
+	kube := fake.NewSimpleClientset(cm)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := New(WithDefaultConfig([]byte(`{"zoneName": "example.net"}`)))
+	cluster := &c.proc.cluster
+	cluster.watch(kube, "cert-manager", "nexus-defaults", stopCh)
+
+	cfg, err := c.config(&extapi.JSON{Raw: []byte(`{"followCNAME": true}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Service != "cluster" || cfg.ZoneName != "example.net" || !cfg.FollowCNAME {
+		t.Errorf("expected cluster defaults under solver defaults under the Issuer's config, got %+v", cfg)
+	}
+
+	// Invalid edits are ignored; valid ones are picked up.
+	cm.Data[defaultsKey] = `{"sevrice": "typo"}`
+	if _, err := kube.CoreV1().ConfigMaps("cert-manager").Update(context.Background(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cm.Data[defaultsKey] = `{"service": "updated"}`
+	if _, err := kube.CoreV1().ConfigMaps("cert-manager").Update(context.Background(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cfg, err = c.config(nil)
+		if err == nil && cfg.Service == "updated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the updated defaults to be picked up, got %+v, %v", cfg, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cluster.lock.RLock()
+	_, kept := cluster.fields["zoneName"]
+	cluster.lock.RUnlock()
+	if kept {
+		t.Error("expected the update to replace the cluster defaults, not merge into them")
+	}
+
+	if err := cluster.load(`{"sevrice": "typo"}`); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+}
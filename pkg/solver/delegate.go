@@ -0,0 +1,84 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// delegateSolver is a second DNS01 webhook that challenges outside the
+// zones Nexus manages are handed to, so estates with several DNS providers
+// can use one Issuer.
+type delegateSolver struct {
+	GroupName  string       `json:"groupName"`
+	SolverName string       `json:"solverName"`
+	Config     *extapi.JSON `json:"config,omitempty"`
+}
+
+// managesName reports whether fqdn is in a zone the config says Nexus
+// manages: zoneName, a zones entry or allowedZones.
+func (cfg *Config) managesName(fqdn string) bool {
+	zones := append([]string(nil), cfg.AllowedZones...)
+	if cfg.ZoneName != "" {
+		zones = append(zones, cfg.ZoneName)
+	}
+	for _, z := range cfg.Zones {
+		zones = append(zones, z.Zone)
+	}
+	_, ok := matchZone(fqdn, zones)
+	return ok
+}
+
+// delegated reports whether ch goes to cfg's delegate instead of Nexus.
+func (cfg *Config) delegated(ch *v1alpha1.ChallengeRequest) bool {
+	return cfg.Delegate != nil && !cfg.managesName(ch.ResolvedFQDN)
+}
+
+// delegate sends ch to d with action, through the API server as
+// cert-manager does, and returns the error the delegate reports.
+func (c *Solver) delegate(ctx context.Context, d *delegateSolver, ch *v1alpha1.ChallengeRequest, action v1alpha1.ChallengeAction) error {
+	req := *ch
+	req.Action = action
+	req.Config = d.Config
+	body, err := json.Marshal(&v1alpha1.ChallengePayload{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ChallengePayload"},
+		Request:  &req,
+	})
+	if err != nil {
+		return err
+	}
+
+	challengeLogger(ctx, ch).V(logf.InfoLevel).Info("delegating challenge", "fqdn", util.UnFqdn(ch.ResolvedFQDN),
+		"group", d.GroupName, "solver", d.SolverName, "action", action)
+	raw, err := c.client.Discovery().RESTClient().Post().
+		AbsPath("/apis", d.GroupName, v1alpha1.SchemeGroupVersion.Version, d.SolverName).
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return fmt.Errorf("delegate %s/%s: %w", d.GroupName, d.SolverName, err)
+	}
+	var payload v1alpha1.ChallengePayload
+	if err = json.Unmarshal(raw, &payload); err != nil {
+		return fmt.Errorf("delegate %s/%s: invalid response: %w", d.GroupName, d.SolverName, err)
+	}
+	if payload.Response == nil {
+		return fmt.Errorf("delegate %s/%s: no response", d.GroupName, d.SolverName)
+	}
+	if !payload.Response.Success {
+		msg := "failed without a reason"
+		if payload.Response.Result != nil && payload.Response.Result.Message != "" {
+			msg = payload.Response.Result.Message
+		}
+		return fmt.Errorf("delegate %s/%s: %s", d.GroupName, d.SolverName, msg)
+	}
+	return nil
+}
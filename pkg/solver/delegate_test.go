@@ -0,0 +1,75 @@
+package solver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestDelegate(t *testing.T) {
+	var got []v1alpha1.ChallengeRequest
+	fail := ""
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/acme.other.example/v1alpha1/route53" {
+			http.NotFound(w, r)
+			return
+		}
+		var payload v1alpha1.ChallengePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Request == nil {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		got = append(got, *payload.Request)
+		payload.Response = &v1alpha1.ChallengeResponse{UID: payload.Request.UID, Success: fail == ""}
+		if fail != "" {
+			payload.Response.Result = &metav1.Status{Status: metav1.StatusFailure, Message: fail}
+		}
+		payload.Request = nil
+		json.NewEncoder(w).Encode(&payload)
+	}))
+	defer apiserver.Close()
+
+	c, server, base := newTestSolver(t, `{
+		"delegate": {"groupName": "acme.other.example", "solverName": "route53", "config": {"region": "eu-west-1"}}
+	}`)
+	c.client = kubernetes.NewForConfigOrDie(&rest.Config{Host: apiserver.URL})
+	request := func(name string) *v1alpha1.ChallengeRequest {
+		ch := *base
+		ch.UID = types.UID("uid-" + name)
+		ch.DNSName = name
+		ch.ResolvedFQDN = "_acme-challenge." + name + "."
+		ch.ResolvedZone = ""
+		return &ch
+	}
+
+	other := request("www.other.org")
+	if err := c.Present(other); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if err := c.CleanUp(other); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if len(got) != 2 || got[0].Action != v1alpha1.ChallengeActionPresent || got[1].Action != v1alpha1.ChallengeActionCleanUp {
+		t.Fatalf("expected Present and CleanUp to be delegated, got %+v", got)
+	}
+	if got[0].UID != other.UID || got[0].ResolvedFQDN != other.ResolvedFQDN || string(got[0].Config.Raw) != `{"region":"eu-west-1"}` {
+		t.Errorf("expected the challenge with the delegate's config, got %+v", got[0])
+	}
+	if len(server.Records()) != 0 {
+		t.Errorf("expected no Nexus records for a delegated challenge, got %v", server.Records())
+	}
+
+	fail = "route53: access denied"
+	if err := c.Present(other); err == nil || !strings.Contains(err.Error(), fail) {
+		t.Errorf("expected the delegate's failure, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package solver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+// Target is where the solver puts the record for a challenge name.
+type Target struct {
+	// Zone is the Nexus domain the record is created in.
+	Zone string
+	// Record is the record name within Zone.
+	Record string
+	// FQDN is the name the record is served at, which differs from the
+	// challenge name when CNAMEs are followed or challengeZone is set.
+	FQDN string
+}
+
+// diagnosticChallenge is the challenge a diagnostic runs as: one for the
+// record at fqdn, issued from namespace. Its zone is looked up as
+// cert-manager would, unless cfg names one, so a lookup failure is
+// reported rather than only logged.
+func (c *Solver) diagnosticChallenge(ctx context.Context, fqdn, namespace string, cfg *Config, cfgJSON []byte, allowAmbientCredentials bool) (*v1alpha1.ChallengeRequest, error) {
+	fqdn = util.ToFqdn(fqdn)
+	if !strings.HasPrefix(fqdn, "_acme-challenge.") {
+		fqdn = "_acme-challenge." + fqdn
+	}
+	ch := &v1alpha1.ChallengeRequest{
+		UID:                     "diagnostic",
+		ResourceNamespace:       namespace,
+		DNSName:                 util.UnFqdn(strings.TrimPrefix(fqdn, "_acme-challenge.")),
+		ResolvedFQDN:            fqdn,
+		AllowAmbientCredentials: allowAmbientCredentials,
+		Config:                  &extapi.JSON{Raw: cfgJSON},
+	}
+	if cfg.ZoneName != "" || cfg.ChallengeZone != "" {
+		return ch, nil
+	}
+	zone, err := c.proc.zones.find(ctx, fqdn)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the zone for %s: %w", fqdn, err)
+	}
+	ch.ResolvedZone = zone
+	return ch, nil
+}
+
+// ResolveTarget works out, the way Present would, where the record for a
+// challenge on fqdn goes under the solver config cfgJSON. fqdn is the
+// name being certified or its _acme-challenge name.
+func (c *Solver) ResolveTarget(ctx context.Context, fqdn string, cfgJSON []byte) (t Target, err error) {
+	cfg, err := c.config(&extapi.JSON{Raw: cfgJSON})
+	if err != nil {
+		return
+	}
+	ch, err := c.diagnosticChallenge(ctx, fqdn, "", &cfg, cfgJSON, true)
+	if err != nil {
+		return
+	}
+	target, err := c.resolveTarget(ctx, ch, &cfg)
+	if err != nil {
+		return
+	}
+	if err = c.proc.checkZonePolicy(target.fqdn, &cfg); err != nil {
+		return
+	}
+	t = Target{Zone: target.domain, Record: target.record, FQDN: target.fqdn}
+	return
+}
+
+// ValidateConfig checks cfgJSON as the solver does before each challenge,
+// for an issuer that may or may not use ambient credentials.
+func (c *Solver) ValidateConfig(cfgJSON []byte, allowAmbientCredentials bool) error {
+	cfg, err := c.config(&extapi.JSON{Raw: cfgJSON})
+	if err != nil {
+		return err
+	}
+	return c.validate(&cfg, allowAmbientCredentials)
+}
+
+// CheckCredentials creates and deletes a throwaway record beside the one a
+// challenge on fqdn would use, with the API key the config resolves to for
+// an issuer in namespace. Nexus has no read-only call to check a key with,
+// so this is the only way to prove it can write the zone.
+func (c *Solver) CheckCredentials(ctx context.Context, fqdn, namespace string, cfgJSON []byte, allowAmbientCredentials bool) (t Target, err error) {
+	cfg, err := c.config(&extapi.JSON{Raw: cfgJSON})
+	if err != nil {
+		return
+	}
+	if err = c.validate(&cfg, allowAmbientCredentials); err != nil {
+		return
+	}
+	ch, err := c.diagnosticChallenge(ctx, fqdn, namespace, &cfg, cfgJSON, allowAmbientCredentials)
+	if err != nil {
+		return
+	}
+	target, err := c.resolveTarget(ctx, ch, &cfg)
+	if err != nil {
+		return
+	}
+	t = Target{Zone: target.domain, Record: target.record, FQDN: target.fqdn}
+	nc, err := c.nexusApiClient(ctx, ch, &cfg, target.domain)
+	if err != nil {
+		return
+	}
+	err = roundTrip(ctx, c.proc, nc, "_nexus-check."+target.record)
+	return
+}
+
+// TestConnection runs CheckCredentials for a record in zone with the
+// solver's default config, which is the operator's own, so ambient
+// credentials are allowed. An apikeysecret in the default config must name
+// its namespace, as for --startup-credential-check. --defaults-configmap
+// is read once rather than watched.
+func (c *Solver) TestConnection(ctx context.Context, zone string) (t Target, err error) {
+	if c.proc.settings.DefaultsConfigMap != "" {
+		if c.client == nil {
+			err = errors.New("--test-connection needs a cluster to read --defaults-configmap from")
+			return
+		}
+		if err = c.proc.loadClusterDefaults(ctx, c.client); err != nil {
+			err = fmt.Errorf("cluster defaults: %w", err)
+			return
+		}
+	}
+	cfg, err := c.config(nil)
+	if err != nil {
+		return
+	}
+	ref := cfg.ApiKeySecretRef
+	if ref.set() && ref.Namespace == "" {
+		err = fmt.Errorf("--test-connection needs apikeysecret.namespace in solver %s's default config", c.Name())
+		return
+	}
+	cfgJSON, err := json.Marshal(map[string]string{"zoneName": util.UnFqdn(zone)})
+	if err != nil {
+		return
+	}
+	return c.CheckCredentials(ctx, zone, ref.Namespace, cfgJSON, true)
+}
+
+// lateTestRecordWait is how long roundTrip waits for an abandoned create
+// to return, so the record it made can be deleted before the process
+// exits.
+var lateTestRecordWait = time.Minute
+
+// roundTrip creates a TXT record with a random value at record and deletes
+// it again. If the delete fails, the error names the record so it can be
+// removed by hand.
+func roundTrip(ctx context.Context, p *Process, nc nexusclient.API, record string) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	value := hex.EncodeToString(buf)
+
+	// late reports the delete of a record made by an abandoned create: nil
+	// if there was nothing to delete.
+	late := make(chan error, 1)
+	id, err := callNexusLate(ctx, p, func() (uuid.UUID, error) {
+		return nc.CreateChallengeRecord(record, value)
+	}, func(id uuid.UUID, err error) {
+		if err != nil {
+			late <- nil
+			return
+		}
+		_, err = callNexus(context.WithoutCancel(ctx), p, func() (struct{}, error) {
+			return struct{}{}, nc.DeleteChallengeRecord(id)
+		})
+		if err != nil {
+			err = fmt.Errorf("ID %s: %w", id, err)
+		}
+		late <- err
+	})
+	if errors.Is(err, errNexusCallAbandoned) {
+		// The create may still go through, and a caller that exits on the
+		// error would leave the record behind.
+		select {
+		case deleteErr := <-late:
+			if deleteErr != nil {
+				return fmt.Errorf("create test record %s: %w; it was created late and deleting it failed, remove it manually: %w", record, err, deleteErr)
+			}
+		case <-time.After(lateTestRecordWait):
+			return fmt.Errorf("create test record %s: %w; it may still be created, check for it and remove it manually", record, err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("create test record %s: %w", record, err)
+	}
+	_, err = callNexus(ctx, p, func() (struct{}, error) {
+		return struct{}{}, nc.DeleteChallengeRecord(id)
+	})
+	if err != nil {
+		return fmt.Errorf("created test record %s (ID %s), but deleting it failed; remove it manually: %w", record, id, err)
+	}
+	return nil
+}
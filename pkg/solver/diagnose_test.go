@@ -0,0 +1,111 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+	"github.com/fudoniten/cert-manager-webhook-nexus/nexustest"
+)
+
+func TestResolveTargetDiagnostic(t *testing.T) {
+	c := New()
+	got, err := c.ResolveTarget(context.Background(), "www.example.com", []byte(`{"service": "svc", "zoneName": "example.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Target{Zone: "example.com", Record: "_acme-challenge.www", FQDN: "_acme-challenge.www.example.com."}
+	if got != want {
+		t.Errorf("ResolveTarget = %+v, expected %+v", got, want)
+	}
+
+	if _, err = c.ResolveTarget(context.Background(), "www.example.com", []byte(`{"zoneName": "example.net"}`)); !errors.Is(err, ErrZoneMismatch) {
+		t.Errorf("expected a zone mismatch, got %v", err)
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	c, server, ch := newTestSolver(t, "")
+
+	target, err := c.CheckCredentials(context.Background(), "www.example.com", "default", ch.Config.Raw, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Zone != "example.com" {
+		t.Errorf("expected the check in example.com, got %+v", target)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Errorf("expected the test record to be deleted, got %v", records)
+	}
+	var creates int
+	for _, req := range server.Requests() {
+		if req.Op == nexustest.OpCreate {
+			creates++
+		}
+	}
+	if creates != 1 {
+		t.Errorf("expected one test record created, got %d", creates)
+	}
+
+	if _, err = c.CheckCredentials(context.Background(), "www.example.com", "", []byte(`{"service": "svc", "zoneName": "example.com"}`), false); err == nil {
+		t.Error("expected ambient credentials to be refused")
+	}
+}
+
+type failingDelete struct{ nexusclient.API }
+
+func (failingDelete) DeleteChallengeRecord(uuid.UUID) error {
+	return errors.New("500 Internal Server Error")
+}
+
+func TestRoundTripReportsLeftoverRecord(t *testing.T) {
+	nc, err := nexustest.NewServer().Client("example.com", "svc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = roundTrip(context.Background(), newProcess(DefaultSettings()), failingDelete{nc}, "_nexus-check"); err == nil {
+		t.Fatal("expected the failed delete to be reported")
+	}
+}
+
+func TestTestConnection(t *testing.T) {
+	c, server, _ := newTestSolver(t, "")
+	c.defaults = []byte(`{"service": "svc", "apikeysecret": {"name": "nexus", "key": "key", "namespace": "default"}}`)
+
+	target, err := c.TestConnection(context.Background(), "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Zone != "example.com" {
+		t.Errorf("expected the test record in example.com, got %+v", target)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Errorf("expected the test record to be deleted, got %v", records)
+	}
+
+	server.FailNext(errors.New("forbidden"))
+	if _, err := c.TestConnection(context.Background(), "example.com"); err == nil {
+		t.Error("expected a failed create to be reported")
+	}
+
+	// A create that outlives its timeout is waited for, and its record
+	// deleted, before TestConnection returns.
+	c.proc.settings.NexusCallTimeout = 20 * time.Millisecond
+	server.CreateDelay = 100 * time.Millisecond
+	if _, err := c.TestConnection(context.Background(), "example.com"); !errors.Is(err, errNexusCallAbandoned) {
+		t.Errorf("expected the slow create to be reported, got %v", err)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Errorf("expected the late test record to be deleted, got %v", records)
+	}
+	server.CreateDelay = 0
+
+	c.defaults = []byte(`{"service": "svc", "apikeysecret": {"name": "nexus", "key": "key"}}`)
+	if _, err := c.TestConnection(context.Background(), "example.com"); err == nil {
+		t.Error("expected an apikeysecret without a namespace to be refused")
+	}
+}
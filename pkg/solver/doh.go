@@ -0,0 +1,91 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dohResolvers are the well-known DNS-over-HTTPS endpoints propagationCheck
+// dohURL can name instead of a URL.
+var dohResolvers = map[string]string{
+	"cloudflare": "https://cloudflare-dns.com/dns-query",
+	"google":     "https://dns.google/dns-query",
+}
+
+const defaultDoHResolver = "cloudflare"
+
+// dohClient sends DNS-over-HTTPS queries; tests swap it for one that
+// trusts their server.
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// dohEndpoint returns the URL for a dohURL setting, which is a name from
+// dohResolvers or an https URL.
+func dohEndpoint(setting string) (string, error) {
+	if setting == "" {
+		setting = defaultDoHResolver
+	}
+	if u, ok := dohResolvers[setting]; ok {
+		return u, nil
+	}
+	u, err := url.Parse(setting)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("%q is not an https URL or one of cloudflare, google", setting)
+	}
+	return setting, nil
+}
+
+// checkDoH reports whether the resolver at endpoint returns value among the
+// TXT records at fqdn. The query is an RFC 8484 POST, so it only needs
+// outbound HTTPS.
+func checkDoH(ctx context.Context, endpoint, fqdn, value string) (bool, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	// The ID is always 0 over DoH, so responses stay cacheable.
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("DoH query to %s failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("DoH query to %s returned %s", endpoint, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return false, err
+	}
+
+	var answer dns.Msg
+	if err = answer.Unpack(body); err != nil {
+		return false, fmt.Errorf("could not decode DoH response from %s: %w", endpoint, err)
+	}
+	if answer.Rcode != dns.RcodeSuccess {
+		return false, fmt.Errorf("DoH query to %s for %s returned %s", endpoint, fqdn, dns.RcodeToString[answer.Rcode])
+	}
+	// The resolver follows CNAMEs, so any TXT record in the answer counts.
+	for _, rr := range answer.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
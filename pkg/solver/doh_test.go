@@ -0,0 +1,78 @@
+package solver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestCheckDoH(t *testing.T) {
+	records := map[string][]string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var query dns.Msg
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(&query)
+		q := query.Question[0]
+		values, ok := records[q.Name]
+		if !ok {
+			resp.Rcode = dns.RcodeNameError
+		}
+		for _, v := range values {
+			resp.Answer = append(resp.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{v},
+			})
+		}
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer server.Close()
+	client := dohClient
+	dohClient = server.Client()
+	defer func() { dohClient = client }()
+
+	fqdn := "_acme-challenge.example.com."
+	cfg := &propagationConfig{Mode: propagationDoH, DoHURL: server.URL}
+	if live, err := cfg.check(context.Background(), "", fqdn, "token"); err == nil || live {
+		t.Errorf("expected an NXDOMAIN error for a missing record, got %v, %v", live, err)
+	}
+	records[fqdn] = []string{"other"}
+	if live, err := cfg.check(context.Background(), "", fqdn, "token"); err != nil || live {
+		t.Errorf("expected the record not to be live, got %v, %v", live, err)
+	}
+	records[fqdn] = []string{"other", "token"}
+	if live, err := cfg.check(context.Background(), "", fqdn, "token"); err != nil || !live {
+		t.Errorf("expected the record to be live, got %v, %v", live, err)
+	}
+}
+
+func TestDoHEndpoint(t *testing.T) {
+	for setting, want := range map[string]string{
+		"":                                  dohResolvers["cloudflare"],
+		"google":                            dohResolvers["google"],
+		"https://dns.example.com/dns-query": "https://dns.example.com/dns-query",
+	} {
+		if got, err := dohEndpoint(setting); err != nil || got != want {
+			t.Errorf("dohEndpoint(%q) = %q, %v; want %q", setting, got, err, want)
+		}
+	}
+	for _, setting := range []string{"quad9", "http://dns.example.com/dns-query", "https://"} {
+		if _, err := dohEndpoint(setting); err == nil {
+			t.Errorf("expected dohEndpoint(%q) to fail", setting)
+		}
+	}
+}
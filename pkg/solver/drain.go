@@ -0,0 +1,44 @@
+package solver
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errShuttingDown = errors.New("webhook is shutting down, retry against another replica")
+
+// inflightTracker counts running Present and CleanUp calls so shutdown can
+// wait for them instead of abandoning half-created records.
+type inflightTracker struct {
+	draining int32
+	wg       sync.WaitGroup
+}
+
+// begin registers a call, failing once shutdown has started. The returned
+// func must be called when the call finishes.
+func (t *inflightTracker) begin() (func(), error) {
+	if atomic.LoadInt32(&t.draining) != 0 {
+		return nil, errShuttingDown
+	}
+	t.wg.Add(1)
+	return t.wg.Done, nil
+}
+
+// drain stops new calls and waits up to timeout for running ones. It
+// reports whether they all finished.
+func (t *inflightTracker) drain(timeout time.Duration) bool {
+	atomic.StoreInt32(&t.draining, 1)
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
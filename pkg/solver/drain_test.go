@@ -0,0 +1,26 @@
+package solver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInflightTracker(t *testing.T) {
+	var tracker inflightTracker
+	done, err := tracker.begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if tracker.drain(10 * time.Millisecond) {
+		t.Errorf("expected drain to time out with a call still running")
+	}
+	if _, err := tracker.begin(); err != errShuttingDown {
+		t.Errorf("expected new calls to be rejected while draining, got %v", err)
+	}
+
+	done()
+	if !tracker.drain(time.Second) {
+		t.Errorf("expected drain to finish once the call is done")
+	}
+}
@@ -0,0 +1,56 @@
+package solver
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envReference = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// expandEnv replaces ${NAME} references in the config fields that name
+// things, rather than secrets or templates. Only variables starting with
+// prefix can be read: Issuers are written by namespace users, and the
+// webhook's environment may hold its own credentials. Empty prefix
+// disables expansion.
+func (cfg *Config) expandEnv(prefix string) (err error) {
+	if prefix == "" {
+		return
+	}
+	fields := []*string{
+		&cfg.Service, &cfg.ZoneName, &cfg.ChallengeZone,
+		&cfg.ApiKeySecretRef.Name, &cfg.ApiKeySecretRef.Namespace,
+	}
+	for i := range cfg.Zones {
+		z := &cfg.Zones[i]
+		fields = append(fields, &z.Zone, &z.Service, &z.ApiKeySecretRef.Name, &z.ApiKeySecretRef.Namespace)
+	}
+	if f := cfg.Fallback; f != nil {
+		fields = append(fields, &f.Service, &f.ApiKeySecretRef.Name, &f.ApiKeySecretRef.Namespace)
+	}
+	for _, field := range fields {
+		if *field, err = expandEnvReferences(*field, prefix); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func expandEnvReferences(value, prefix string) (expanded string, err error) {
+	expanded = envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		if !strings.HasPrefix(name, prefix) {
+			if err == nil {
+				err = fmt.Errorf("%s: only variables starting with %s can be used in solver config", ref, prefix)
+			}
+			return ref
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("%s: variable is not set in the webhook's environment", ref)
+		}
+		return v
+	})
+	return
+}
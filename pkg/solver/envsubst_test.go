@@ -0,0 +1,37 @@
+package solver
+
+import (
+	"testing"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("NEXUS_CONFIG_ENV", "staging")
+	t.Setenv("NEXUS_API_KEY", "secret")
+
+	c := New()
+	cfg, err := c.config(&extapi.JSON{Raw: []byte(`{
+		"service": "webhook-${NEXUS_CONFIG_ENV}",
+		"apikeysecret": {"name": "nexus-${NEXUS_CONFIG_ENV}", "key": "key-${NEXUS_CONFIG_ENV}"},
+		"zones": [{"zone": "${NEXUS_CONFIG_ENV}.example.com", "apikeysecret": {"name": "nexus", "key": "key"}}]
+	}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Service != "webhook-staging" || cfg.ApiKeySecretRef.Name != "nexus-staging" || cfg.Zones[0].Zone != "staging.example.com" {
+		t.Errorf("expected references to be expanded, got %+v", cfg)
+	}
+	if cfg.ApiKeySecretRef.Key != "key-${NEXUS_CONFIG_ENV}" {
+		t.Errorf("expected the secret key to be left alone, got %q", cfg.ApiKeySecretRef.Key)
+	}
+
+	for _, raw := range []string{
+		`{"service": "${NEXUS_API_KEY}"}`,
+		`{"service": "${NEXUS_CONFIG_UNSET}"}`,
+	} {
+		if cfg, err := c.config(&extapi.JSON{Raw: []byte(raw)}); err == nil {
+			t.Errorf("%s: expected an error, got %+v", raw, cfg)
+		}
+	}
+}
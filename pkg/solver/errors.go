@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// Errors the solver wraps with details about the failing challenge. Match
+// them with errors.Is.
+var (
+	// ErrSecretNotFound means the Secret named by apikeysecret, or the key
+	// within it, doesn't exist.
+	ErrSecretNotFound = errors.New("api key secret not found")
+
+	// ErrZoneMismatch means a challenge record falls outside the zone it
+	// would be created in.
+	ErrZoneMismatch = errors.New("challenge not in zone")
+
+	// ErrZoneNotAllowed means the zone policy refuses a challenge record.
+	ErrZoneNotAllowed = errors.New("zone not allowed")
+
+	// ErrNexusUnavailable means Nexus couldn't be reached, or kept failing
+	// with a retryable error until the retries ran out.
+	ErrNexusUnavailable = errors.New("nexus unavailable")
+
+	// ErrRetryBudgetExhausted means a challenge failed as many times, or for
+	// as long, as retry.challengeAttempts or retry.challengeTimeout allow.
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+)
+
+// reasonError is what Present and CleanUp return for failures they can
+// name: cert-manager shows the error as the Challenge's reason, where a
+// short phrase reads better than the full chain. The full error is logged
+// and goes into the Challenge's Event.
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.reason }
+
+func (e *reasonError) Unwrap() error { return e.err }
+
+// failureReason returns the phrase for err, or "" if it has none.
+func failureReason(err error) string {
+	var retryLater *retryLaterError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrRetryBudgetExhausted):
+		return "nexus: retry budget exhausted"
+	case errors.As(err, &retryLater):
+		return fmt.Sprintf("nexus: busy, retry in %s", retryLater.after.Round(time.Second))
+	case errors.Is(err, ErrZoneNotAllowed):
+		return "nexus: zone not allowed by policy"
+	case errors.Is(err, ErrZoneMismatch):
+		return "nexus: zone not managed"
+	case apierrors.IsForbidden(err):
+		return "nexus: not allowed to read api key secret"
+	case errors.Is(err, ErrSecretNotFound):
+		return "nexus: api key secret not found"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "nexus: timed out"
+	case errors.Is(err, ErrNexusUnavailable):
+		return "nexus: api unavailable"
+	}
+	if code, ok := statusCode(err); ok {
+		switch code {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return "nexus: invalid credentials"
+		case http.StatusNotFound:
+			return "nexus: zone or service not found"
+		case http.StatusConflict:
+			return "nexus: conflicting record"
+		}
+	}
+	return ""
+}
+
+// withFailureReason logs err and replaces it with its phrase, if it has
+// one. Other errors, such as invalid configs, are returned as they are.
+func withFailureReason(ctx context.Context, ch *v1alpha1.ChallengeRequest, err error) error {
+	reason := failureReason(err)
+	if reason == "" {
+		return err
+	}
+	challengeLogger(ctx, ch).Error(err, "challenge failed", "reason", reason)
+	return &reasonError{reason: reason, err: err}
+}
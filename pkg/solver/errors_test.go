@@ -0,0 +1,43 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{fmt.Errorf("%w: record x is outside the allowed zones", ErrZoneNotAllowed), "nexus: zone not allowed by policy"},
+		{fmt.Errorf("%w: a.example.net is not under example.com", ErrZoneMismatch), "nexus: zone not managed"},
+		{fmt.Errorf("%w: %w", ErrSecretNotFound, apierrors.NewNotFound(corev1.Resource("secrets"), "nexus")), "nexus: api key secret not found"},
+		{apierrors.NewForbidden(corev1.Resource("secrets"), "nexus", errors.New("no RBAC")), "nexus: not allowed to read api key secret"},
+		{fmt.Errorf("%w: %w", ErrNexusUnavailable, errors.New("503 Service Unavailable")), "nexus: api unavailable"},
+		{fmt.Errorf("nexus call abandoned: %w", context.DeadlineExceeded), "nexus: timed out"},
+		{errors.New("failed to create record: 401 Unauthorized"), "nexus: invalid credentials"},
+		{errors.New("invalid solver config: service is required"), ""},
+	}
+	for _, test := range tests {
+		if got := failureReason(test.err); got != test.reason {
+			t.Errorf("failureReason(%v) = %q, expected %q", test.err, got, test.reason)
+		}
+	}
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com."}
+	err := withFailureReason(context.Background(), ch, tests[0].err)
+	if err.Error() != tests[0].reason || !errors.Is(err, ErrZoneNotAllowed) {
+		t.Errorf("expected the reason, wrapping the original error, got %v", err)
+	}
+	if err := withFailureReason(context.Background(), ch, tests[len(tests)-1].err); err != tests[len(tests)-1].err {
+		t.Errorf("expected errors without a reason to pass through, got %v", err)
+	}
+}
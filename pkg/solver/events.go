@@ -0,0 +1,67 @@
+package solver
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmscheme "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/scheme"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+const (
+	reasonPresentFailed = "PresentFailed"
+	reasonCleanUpFailed = "CleanUpFailed"
+)
+
+// eventRecorder publishes solver failures as Events on the Challenge being
+// solved, so they show up in `kubectl describe challenge`.
+type eventRecorder struct {
+	proc       *Process
+	challenges *challengeLister
+	recorder   record.EventRecorder
+}
+
+func newEventRecorder(p *Process, kubeClientConfig *rest.Config, kube kubernetes.Interface, stopCh <-chan struct{}) (*eventRecorder, error) {
+	challenges, err := p.challengeLister(kubeClientConfig, stopCh)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kube.CoreV1().Events("")})
+	go func() {
+		<-stopCh
+		broadcaster.Shutdown()
+	}()
+
+	return &eventRecorder{
+		proc:       p,
+		challenges: challenges,
+		recorder:   broadcaster.NewRecorder(cmscheme.Scheme, corev1.EventSource{Component: "cert-manager-webhook-nexus"}),
+	}, nil
+}
+
+// failure records err against the Challenge matching ch. The request doesn't
+// name its Challenge, so it is looked up by DNS name and key. ctx only
+// supplies the request ID: the failure may be that ctx timed out.
+func (r *eventRecorder) failure(ctx context.Context, ch *v1alpha1.ChallengeRequest, reason string, err error) {
+	ctx, cancel := r.proc.withKubeTimeout(context.WithoutCancel(ctx))
+	defer cancel()
+
+	challenge, lookupErr := r.challenges.find(ctx, ch.DNSName, ch.Key)
+	if lookupErr != nil {
+		challengeLogger(ctx, ch).Error(lookupErr, "could not find challenge to record event on")
+		return
+	}
+	if challenge == nil {
+		challengeLogger(ctx, ch).V(logf.InfoLevel).Info("no matching challenge to record event on")
+		return
+	}
+	r.recorder.Event(challenge, corev1.EventTypeWarning, reason, err.Error())
+}
@@ -0,0 +1,51 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func init() {
+	registerCredentialProvider(providerExternal, func(c *Solver) CredentialProvider { return externalProvider{c} })
+}
+
+// externalProvider runs the CredentialCommand to fetch the key from tooling
+// the webhook doesn't know about. The command is set by whoever deploys the
+// webhook, never by the issuer, and is told which challenge it is serving
+// through NEXUS_* environment variables.
+type externalProvider struct {
+	c *Solver
+}
+
+func (externalProvider) Ambient() bool { return true }
+
+func (p externalProvider) APIKey(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config) (string, error) {
+	command := p.c.proc.settings.CredentialCommand
+	if command == "" {
+		return "", errors.New("external credential provider requires --credential-command")
+	}
+
+	ctx, cancel := p.c.proc.withKubeTimeout(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"NEXUS_NAMESPACE="+ch.ResourceNamespace,
+		"NEXUS_ZONE="+ch.ResolvedZone,
+		"NEXUS_SERVICE="+cfg.Service,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
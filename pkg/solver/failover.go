@@ -0,0 +1,50 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+// fallbackService is a second Nexus service Present creates records in
+// when the primary one keeps failing.
+type fallbackService struct {
+	Service string `json:"service"`
+	// ApiKeySecretRef, if unset, means the key the primary service uses.
+	ApiKeySecretRef secretKeyRef `json:"apikeysecret,omitempty"`
+}
+
+// backendFallback marks a record created in the fallback service. The
+// primary service has no name, so records stored before fallbacks existed
+// are still found there.
+const backendFallback = "fallback"
+
+// backendClient returns a client for the service backend names: the
+// primary one if it's empty, or cfg.Fallback. Like nexusApiClient, it
+// updates cfg to the settings of the service it picked.
+func (c *Solver) backendClient(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config, domain, backend string) (nexusclient.API, error) {
+	switch backend {
+	case "":
+		return c.nexusApiClient(ctx, ch, cfg, domain)
+	case backendFallback:
+		if cfg.Fallback == nil {
+			return nil, errors.New("record was created in the fallback service, which is no longer configured")
+		}
+		fb := *cfg
+		fb.applyZoneCredentials(domain)
+		fb.Zones, fb.Fallback = nil, nil
+		fb.Service = cfg.Fallback.Service
+		if cfg.Fallback.ApiKeySecretRef.set() {
+			fb.ApiKeySecretRef = cfg.Fallback.ApiKeySecretRef
+			fb.CredentialSource = credentialSource{}
+		}
+		*cfg = fb
+		return c.nexusApiClient(ctx, ch, cfg, domain)
+	default:
+		return nil, fmt.Errorf("record was created in unknown service %q", backend)
+	}
+}
@@ -0,0 +1,61 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+	"github.com/fudoniten/cert-manager-webhook-nexus/nexustest"
+)
+
+func TestFallbackService(t *testing.T) {
+	c, primary, ch := newTestSolver(t, `{
+		"retry": {"maxAttempts": 1},
+		"fallback": {"service": "svc-dr", "apikeysecret": {"name": "nexus-dr", "key": "key"}}
+	}`)
+	if _, err := c.client.CoreV1().Secrets("default").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus-dr", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("dr-secret")},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fallback := nexustest.NewServer()
+	var keys []string
+	c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) {
+		keys = append(keys, string(key))
+		if service == "svc-dr" {
+			return fallback.Client(domain, service, key)
+		}
+		return primary.Client(domain, service, key)
+	}
+
+	primary.FailNext(errors.New("503 Service Unavailable"))
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if len(primary.Records()) != 0 || len(fallback.Records()) != 1 {
+		t.Fatalf("expected the record in the fallback service, got primary %v, fallback %v", primary.Records(), fallback.Records())
+	}
+	if len(keys) != 2 || keys[1] != "dr-secret" {
+		t.Errorf("expected the fallback client to use the fallback key, got %q", keys)
+	}
+
+	// CleanUp has to find the record's service from the store after a
+	// restart, while the primary service is healthy again.
+	c.challenges = nil
+	if err := c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if records := fallback.Records(); len(records) != 0 {
+		t.Errorf("expected CleanUp to delete the record from the fallback service, got %v", records)
+	}
+	for _, req := range primary.Requests() {
+		if req.Op == nexustest.OpDelete {
+			t.Errorf("expected no delete against the primary service, got %+v", req)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package solver
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// startOrphanGC periodically cleans up records left behind when cert-manager
+// never called CleanUp, or CleanUp failed, e.g. because the webhook crashed
+// or the Challenge was deleted while it was down.
+func (c *Solver) startOrphanGC(challenges *challengeLister, interval time.Duration, stopCh <-chan struct{}) {
+	go wait.Until(func() { c.collectOrphans(c.baseContext(), challenges) }, interval, stopCh)
+}
+
+// collectOrphans runs one cleanup pass over the store. Only records the
+// webhook presented can be found this way: Nexus can't list records, so
+// anything missing from the store is out of reach.
+func (c *Solver) collectOrphans(ctx context.Context, challenges *challengeLister) {
+	listCtx, cancel := c.proc.withKubeTimeout(ctx)
+	stored, err := c.store.List(listCtx)
+	cancel()
+	if err != nil {
+		contextLogger(ctx).Error(err, "could not list stored challenges for garbage collection")
+		return
+	}
+
+	for _, sc := range stored {
+		ch := sc.Request
+		if sc.solver() != c.Name() {
+			// Another solver's record; only it has the defaults to clean it up.
+			continue
+		}
+		if ch == nil || sc.PresentedAt.IsZero() || time.Since(sc.PresentedAt) < c.proc.settings.OrphanGCMinAge {
+			continue
+		}
+		log := challengeLogger(ctx, ch).WithValues("challengeId", sc.ID)
+
+		findCtx, cancel := c.proc.withKubeTimeout(ctx)
+		live, err := challenges.find(findCtx, ch.DNSName, ch.Key)
+		cancel()
+		if err != nil {
+			log.Error(err, "could not check whether challenge still exists")
+			return
+		}
+		if live != nil {
+			continue
+		}
+
+		log.V(logf.InfoLevel).Info("cleaning up orphaned record", "age", time.Since(sc.PresentedAt))
+		if err := c.CleanUp(ch); err != nil {
+			log.Error(err, "failed to clean up orphaned record")
+			continue
+		}
+		orphansCollectedTotal.Inc()
+	}
+}
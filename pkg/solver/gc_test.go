@@ -0,0 +1,60 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+)
+
+func TestCollectOrphans(t *testing.T) {
+	c, server, base := newTestSolver(t, "")
+	request := func(name string) *v1alpha1.ChallengeRequest {
+		ch := *base
+		ch.DNSName = name + ".example.com"
+		ch.ResolvedFQDN = fmt.Sprintf("_acme-challenge.%s.example.com.", name)
+		ch.Key = name + "-token"
+		return &ch
+	}
+	live, orphan := request("live"), request("orphan")
+	for _, ch := range []*v1alpha1.ChallengeRequest{live, orphan} {
+		if err := c.Present(ch); err != nil {
+			t.Fatalf("Present: %v", err)
+		}
+	}
+	// Forget the in-memory state, as after a restart.
+	c.challenges = nil
+
+	cm := cmfake.NewSimpleClientset(&cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Name: "live", Namespace: "default"},
+		Spec:       cmacme.ChallengeSpec{DNSName: live.DNSName, Key: live.Key},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	challenges, err := newChallengeLister(cm, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.proc.settings.OrphanGCMinAge = 0
+
+	c.collectOrphans(context.Background(), challenges)
+
+	records := server.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected only the live record to remain, got %v", records)
+	}
+	for _, r := range records {
+		if r.Value != live.Key {
+			t.Errorf("expected the live record to remain, got %+v", r)
+		}
+	}
+	stored, err := c.store.List(context.Background())
+	if err != nil || len(stored) != 1 {
+		t.Errorf("expected the orphan to be removed from the store, got %v, %v", stored, err)
+	}
+}
@@ -0,0 +1,138 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+const readinessCheckTimeout = 5 * time.Second
+
+// credentialCheckInterval is how often awaitCredentials retries.
+var credentialCheckInterval = 10 * time.Second
+
+// healthServer answers liveness and readiness probes. The webhook is ready
+// once the solver is initialized and, if configured, Nexus answers without
+// a server error. With several solvers it reports ready once the first is
+// initialized.
+type healthServer struct {
+	ready  int32
+	client *http.Client
+	// readinessURL, if set, must answer for the webhook to be ready.
+	readinessURL string
+	log          logr.Logger
+}
+
+func (h *healthServer) setReady() {
+	atomic.StoreInt32(&h.ready, 1)
+}
+
+func (h *healthServer) setUnready() {
+	atomic.StoreInt32(&h.ready, 0)
+}
+
+func (h *healthServer) checkReady(ctx context.Context) error {
+	if atomic.LoadInt32(&h.ready) == 0 {
+		return errors.New("solver not initialized or shutting down")
+	}
+	if h.readinessURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, h.readinessURL, nil)
+	if err != nil {
+		return err
+	}
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNexusUnavailable, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%w: %s", ErrNexusUnavailable, resp.Status)
+	}
+	return nil
+}
+
+func (h *healthServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := h.checkReady(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// start serves probes on addr until stopCh is closed.
+func (h *healthServer) start(addr string, stopCh <-chan struct{}) {
+	srv := &http.Server{Addr: addr, Handler: h.handler()}
+	go func() {
+		<-stopCh
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			h.log.Error(err, "health probe server failed", "address", addr)
+		}
+	}()
+}
+
+// awaitCredentials marks h ready once the API key for c's default service
+// can be read and a Nexus client built from it, retrying until stopCh is
+// closed. nexus-go has no call that authenticates without changing
+// records, so the key isn't checked against Nexus itself; with
+// --readiness-nexus-url, /readyz still checks that Nexus is reachable.
+// A solver whose default config names no service has nothing to check
+// and is ready straight away.
+func (c *Solver) awaitCredentials(h *healthServer, stopCh <-chan struct{}) error {
+	cfg, err := c.config(nil)
+	if err != nil {
+		return err
+	}
+	if cfg.Service == "" {
+		h.setReady()
+		return nil
+	}
+	ref := cfg.ApiKeySecretRef
+	if ref.set() && ref.Namespace == "" {
+		return fmt.Errorf("--startup-credential-check needs apikeysecret.namespace in solver %s's default config", c.Name())
+	}
+	// The default config is the operator's own, so ambient credentials
+	// are allowed.
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: ref.Namespace, AllowAmbientCredentials: true}
+
+	log := c.logger().WithValues("solver", c.Name(), "service", cfg.Service)
+	go wait.PollUntilContextCancel(wait.ContextForChannel(stopCh), credentialCheckInterval, true, func(ctx context.Context) (bool, error) {
+		attempt := cfg
+		if _, err := c.nexusApiClient(ctx, ch, &attempt, util.UnFqdn(cfg.ZoneName)); err != nil {
+			log.Error(err, "not ready: could not load the Nexus API key; retrying", "interval", credentialCheckInterval)
+			return false, nil
+		}
+		log.Info("Nexus API key loaded")
+		h.setReady()
+		return true, nil
+	})
+	return nil
+}
@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+func TestHealthServer(t *testing.T) {
+	nexusStatus := http.StatusOK
+	nexus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(nexusStatus)
+	}))
+	defer nexus.Close()
+
+	h := &healthServer{}
+	probes := httptest.NewServer(h.handler())
+	defer probes.Close()
+
+	probe := func(path string) int {
+		resp, err := http.Get(probes.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("expected /healthz to pass, got %d", code)
+	}
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to fail before initialization, got %d", code)
+	}
+
+	h.setReady()
+	h.readinessURL = nexus.URL
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("expected /readyz to pass, got %d", code)
+	}
+
+	nexusStatus = http.StatusBadGateway
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to fail while nexus is erroring, got %d", code)
+	}
+}
+
+func TestAwaitCredentials(t *testing.T) {
+	defer func(interval time.Duration) { credentialCheckInterval = interval }(credentialCheckInterval)
+	credentialCheckInterval = 10 * time.Millisecond
+	kube := fake.NewSimpleClientset()
+	c := New(WithClient(kube),
+		WithDefaultConfig([]byte(`{"service": "svc", "apikeysecret": {"name": "nexus", "key": "key", "namespace": "cert-manager"}}`)))
+	c.newClient = func(string, string, []byte) (nexusclient.API, error) { return nil, nil }
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	h := &healthServer{}
+	if err := c.awaitCredentials(h, stopCh); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * credentialCheckInterval)
+	if err := h.checkReady(context.Background()); err == nil {
+		t.Errorf("expected not to be ready without the API key secret")
+	}
+
+	kube.CoreV1().Secrets("cert-manager").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus", Namespace: "cert-manager"},
+		Data:       map[string][]byte{"key": []byte("secret")},
+	}, metav1.CreateOptions{})
+	deadline := time.Now().Add(time.Second)
+	for h.checkReady(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected to be ready once the API key secret exists")
+		}
+		time.Sleep(credentialCheckInterval)
+	}
+
+	c.defaults = []byte(`{"service": "svc", "apikeysecret": {"name": "nexus", "key": "key"}}`)
+	if err := c.awaitCredentials(&healthServer{}, stopCh); err == nil {
+		t.Errorf("expected a secret without a namespace to be refused")
+	}
+	c.defaults = nil
+	h = &healthServer{}
+	if err := c.awaitCredentials(h, stopCh); err != nil || h.checkReady(context.Background()) != nil {
+		t.Errorf("expected a solver without a default service to be ready, got %v", err)
+	}
+}
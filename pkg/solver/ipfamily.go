@@ -0,0 +1,149 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/miekg/dns"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// ipFamily is the address family nameservers are preferably reached over:
+// ipFamilyIPv4, ipFamilyIPv6, or empty to leave it to the resolver.
+type ipFamily string
+
+const (
+	ipFamilyIPv4 = "IPv4"
+	ipFamilyIPv6 = "IPv6"
+)
+
+// nameserverPort is the port authoritative nameservers are queried on.
+var nameserverPort = "53"
+
+// recursiveNameservers returns the pod's resolvers, those of the preferred
+// family first.
+func (f ipFamily) recursiveNameservers() []string {
+	return f.preferFamily(util.RecursiveNameservers)
+}
+
+// preferFamily orders nameservers (host:port) so those of the preferred
+// family come first. DNSQuery moves on to the next server after an error,
+// so this keeps unreachable ones from costing a timeout on every query.
+func (f ipFamily) preferFamily(nameservers []string) []string {
+	if f == "" {
+		return nameservers
+	}
+	sorted := slices.Clone(nameservers)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		pa, pb := f.isPreferred(a), f.isPreferred(b)
+		switch {
+		case pa == pb:
+			return 0
+		case pa:
+			return -1
+		}
+		return 1
+	})
+	return sorted
+}
+
+func (f ipFamily) isPreferred(nameserver string) bool {
+	host, _, err := net.SplitHostPort(nameserver)
+	if err != nil {
+		host = nameserver
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return (ip.To4() == nil) == (f == ipFamilyIPv6)
+}
+
+// preCheckDNS is util.PreCheckDNS, except that with a preferred family the
+// authoritative nameservers are queried over that family where they have
+// an address in it. cert-manager dials them by name, and over UDP the dial
+// succeeds whichever address the resolver picks, so a v6-only pod can wait
+// out a timeout on each v4 address.
+func (f ipFamily) preCheckDNS(ctx context.Context, fqdn, value string, nameservers []string, useAuthoritative bool) (bool, error) {
+	nameservers = f.preferFamily(nameservers)
+	if !useAuthoritative || f == "" {
+		return util.PreCheckDNS(ctx, fqdn, value, nameservers, useAuthoritative)
+	}
+	authoritative, err := f.authoritativeNameservers(ctx, fqdn, nameservers)
+	if err != nil {
+		return false, err
+	}
+	return util.PreCheckDNS(ctx, fqdn, value, authoritative, false)
+}
+
+// authoritativeNameservers returns an address (host:port) for each
+// nameserver of fqdn's zone, in the preferred family if it has one.
+func (f ipFamily) authoritativeNameservers(ctx context.Context, fqdn string, nameservers []string) ([]string, error) {
+	zone, err := util.FindZoneByFqdn(ctx, fqdn, nameservers)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the zone for %q: %w", fqdn, err)
+	}
+	r, err := util.DNSQuery(ctx, zone, dns.TypeNS, nameservers, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, rr := range r.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		addr, err := f.nameserverAddress(ctx, ns.Ns, nameservers)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("could not determine authoritative nameservers for %q", fqdn)
+	}
+	return addrs, nil
+}
+
+// nameserverAddress resolves host to an address in the preferred family,
+// or the other one if it has none.
+func (f ipFamily) nameserverAddress(ctx context.Context, host string, nameservers []string) (string, error) {
+	types := []uint16{dns.TypeAAAA, dns.TypeA}
+	if f == ipFamilyIPv4 {
+		types = []uint16{dns.TypeA, dns.TypeAAAA}
+	}
+	for _, t := range types {
+		r, err := addressQuery(ctx, host, t, nameservers)
+		if err != nil {
+			return "", err
+		}
+		for _, rr := range r.Answer {
+			switch rr := rr.(type) {
+			case *dns.AAAA:
+				return net.JoinHostPort(rr.AAAA.String(), nameserverPort), nil
+			case *dns.A:
+				return net.JoinHostPort(rr.A.String(), nameserverPort), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("nameserver %s has no address", host)
+}
+
+// addressQuery asks each of nameservers in turn for host's A or AAAA
+// records, which util.DNSQuery doesn't look up.
+func addressQuery(ctx context.Context, host string, rtype uint16, nameservers []string) (r *dns.Msg, err error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(host), rtype)
+	client := &dns.Client{Timeout: util.DNSTimeout}
+	for _, ns := range nameservers {
+		if r, _, err = client.ExchangeContext(ctx, m, ns); err == nil {
+			return
+		}
+	}
+	err = fmt.Errorf("could not look up %s for %s: %w", dns.TypeToString[rtype], host, err)
+	return
+}
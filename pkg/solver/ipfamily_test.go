@@ -0,0 +1,88 @@
+package solver
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// serveV6OnlyZone answers for v6only.test. on [::1], as the only resolver
+// and nameserver reachable from an IPv6-only pod. Its nameserver also has
+// an IPv4 address, which nothing listens on.
+func serveV6OnlyZone(t *testing.T) string {
+	pc, err := net.ListenPacket("udp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	rr := func(s string) dns.RR {
+		r, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	records := map[uint16]map[string][]dns.RR{
+		dns.TypeSOA:  {"v6only.test.": {rr("v6only.test. 60 IN SOA ns1.v6only.test. admin.v6only.test. 1 60 60 60 60")}},
+		dns.TypeNS:   {"v6only.test.": {rr("v6only.test. 60 IN NS ns1.v6only.test.")}},
+		dns.TypeA:    {"ns1.v6only.test.": {rr("ns1.v6only.test. 60 IN A 192.0.2.1")}},
+		dns.TypeAAAA: {"ns1.v6only.test.": {rr("ns1.v6only.test. 60 IN AAAA ::1")}},
+		dns.TypeTXT:  {"_acme-challenge.v6only.test.": {rr(`_acme-challenge.v6only.test. 60 IN TXT "token"`)}},
+	}
+	names := map[string]bool{"v6only.test.": true, "ns1.v6only.test.": true, "_acme-challenge.v6only.test.": true}
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if !names[q.Name] {
+			m.Rcode = dns.RcodeNameError
+		}
+		m.Answer = records[q.Qtype][q.Name]
+		w.WriteMsg(m)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestPreCheckDNSIPv6Only(t *testing.T) {
+	addr := serveV6OnlyZone(t)
+	_, port, _ := net.SplitHostPort(addr)
+	defer func(p string, timeout time.Duration) {
+		nameserverPort, util.DNSTimeout = p, timeout
+	}(nameserverPort, util.DNSTimeout)
+	nameserverPort = port
+	util.DNSTimeout = 200 * time.Millisecond
+
+	fqdn := "_acme-challenge.v6only.test."
+	if live, err := ipFamily(ipFamilyIPv6).preCheckDNS(context.Background(), fqdn, "token", []string{addr}, true); err != nil || !live {
+		t.Errorf("expected the record to be found over IPv6, got %v, %v", live, err)
+	}
+	if live, err := ipFamily(ipFamilyIPv4).preCheckDNS(context.Background(), fqdn, "token", []string{addr}, true); err == nil || live {
+		t.Errorf("expected the unreachable IPv4 nameserver to be queried, got %v, %v", live, err)
+	}
+}
+
+func TestPreferFamily(t *testing.T) {
+	nameservers := []string{"10.0.0.10:53", "[fd00::10]:53", "10.0.0.11:53", "[fd00::11]:53"}
+
+	if got := ipFamily("").preferFamily(nameservers); !slices.Equal(got, nameservers) {
+		t.Errorf("expected the resolver order to be kept, got %v", got)
+	}
+	if got, want := ipFamily(ipFamilyIPv6).preferFamily(nameservers), []string{"[fd00::10]:53", "[fd00::11]:53", "10.0.0.10:53", "10.0.0.11:53"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got, want := ipFamily(ipFamilyIPv4).preferFamily(nameservers), []string{"10.0.0.10:53", "10.0.0.11:53", "[fd00::10]:53", "[fd00::11]:53"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	s := DefaultSettings()
+	s.PreferIPFamily = "IPv5"
+	if err := s.validate(); err == nil {
+		t.Errorf("expected an unknown family to be rejected")
+	}
+}
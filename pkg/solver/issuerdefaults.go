@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// nexusIssuerDefaultsResource is the NexusIssuerDefaults CRD the chart
+// installs.
+var nexusIssuerDefaultsResource = schema.GroupVersionResource{
+	Group:    "nexus.fudo.org",
+	Version:  "v1alpha1",
+	Resource: "nexusissuerdefaults",
+}
+
+// nexusIssuerDefaultsSpec holds config fields for one issuer, so platform
+// teams can manage them apart from the Issuer.
+type nexusIssuerDefaultsSpec struct {
+	// IssuerKind, if set, limits the defaults to an Issuer or a
+	// ClusterIssuer, for when both kinds share a name.
+	IssuerKind string          `json:"issuerKind,omitempty"`
+	Config     json.RawMessage `json:"config,omitempty"`
+}
+
+// issuerDefaults finds the defaults for a challenge's issuer. The request
+// doesn't name its issuer, so it's read from the matching Challenge.
+type issuerDefaults struct {
+	proc       *Process
+	challenges *challengeLister
+	dynamic    dynamic.Interface
+}
+
+// lookup returns the config fields for ch's issuer, or nil if it has none.
+func (d *issuerDefaults) lookup(ctx context.Context, ch *v1alpha1.ChallengeRequest) (json.RawMessage, error) {
+	ctx, cancel := d.proc.withKubeTimeout(ctx)
+	defer cancel()
+
+	challenge, err := d.challenges.find(ctx, ch.DNSName, ch.Key)
+	if err != nil {
+		return nil, fmt.Errorf("could not find challenge to look up issuer defaults: %w", err)
+	}
+	if challenge == nil {
+		return nil, nil
+	}
+	issuer := challenge.Spec.IssuerRef
+	kind := issuer.Kind
+	if kind == "" {
+		kind = "Issuer"
+	}
+
+	obj, err := d.dynamic.Resource(nexusIssuerDefaultsResource).Namespace(ch.ResourceNamespace).Get(ctx, issuer.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read issuer defaults %s/%s: %w", ch.ResourceNamespace, issuer.Name, err)
+	}
+	raw, err := json.Marshal(obj.Object["spec"])
+	if err != nil {
+		return nil, err
+	}
+	var spec nexusIssuerDefaultsSpec
+	if err = json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("error decoding issuer defaults %s/%s: %w", ch.ResourceNamespace, issuer.Name, err)
+	}
+	if spec.IssuerKind != "" && spec.IssuerKind != kind {
+		return nil, nil
+	}
+	challengeLogger(ctx, ch).V(logf.DebugLevel).Info("applying issuer defaults", "issuer", issuer.Name, "kind", kind)
+	return spec.Config, nil
+}
+
+// challengeConfig decodes ch's config on top of its issuer's defaults, if
+// --issuer-defaults is set.
+func (c *Solver) challengeConfig(ctx context.Context, ch *v1alpha1.ChallengeRequest) (cfg Config, err error) {
+	var issuer json.RawMessage
+	if c.issuerDefaults != nil {
+		if issuer, err = c.issuerDefaults.lookup(ctx, ch); err != nil {
+			return
+		}
+	}
+	return c.layeredConfig(issuer, ch.Config)
+}
@@ -0,0 +1,117 @@
+package solver
+
+import (
+	"context"
+	"testing"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+)
+
+func issuerDefaultsObject(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "nexus.fudo.org/v1alpha1",
+		"kind":       "NexusIssuerDefaults",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       spec,
+	}}
+}
+
+func TestChallengeConfigIssuerDefaults(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{
+		ResourceNamespace: "team-a",
+		DNSName:           "www.example.com",
+		Key:               "token",
+		Config:            &extapi.JSON{Raw: []byte(`{"service": "svc", "apikeysecret": {"name": "nexus", "key": "key"}}`)},
+	}
+	cm := cmfake.NewSimpleClientset(&cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "team-a"},
+		Spec: cmacme.ChallengeSpec{
+			DNSName:   ch.DNSName,
+			Key:       ch.Key,
+			IssuerRef: cmmeta.ObjectReference{Name: "letsencrypt", Kind: "Issuer"},
+		},
+	})
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{nexusIssuerDefaultsResource: "NexusIssuerDefaultsList"})
+	for _, obj := range []*unstructured.Unstructured{
+		issuerDefaultsObject("team-a", "letsencrypt", map[string]interface{}{
+			"config": map[string]interface{}{"service": "team-svc", "zoneName": "example.com", "challengeZone": "acme.example.com"},
+		}),
+		issuerDefaultsObject("team-b", "letsencrypt", map[string]interface{}{
+			"config": map[string]interface{}{"zoneName": "other.com"},
+		}),
+	} {
+		if _, err := dyn.Resource(nexusIssuerDefaultsResource).Namespace(obj.GetNamespace()).Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("create NexusIssuerDefaults: %v", err)
+		}
+	}
+	c := New(WithDefaultConfig([]byte(`{"zoneName": "default.com", "challengeZone": "acme.default.com"}`)))
+
+	cfg, err := c.challengeConfig(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("challengeConfig: %v", err)
+	}
+	if cfg.ZoneName != "default.com" || cfg.ChallengeZone != "acme.default.com" {
+		t.Errorf("expected the solver defaults without --issuer-defaults, got %+v", cfg)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	challenges, err := newChallengeLister(cm, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.issuerDefaults = &issuerDefaults{proc: c.proc, challenges: challenges, dynamic: dyn}
+	cfg, err = c.challengeConfig(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("challengeConfig: %v", err)
+	}
+	if cfg.ZoneName != "example.com" || cfg.ChallengeZone != "acme.example.com" {
+		t.Errorf("expected the issuer defaults over the solver defaults, got %+v", cfg)
+	}
+	if cfg.Service != "svc" {
+		t.Errorf("expected the challenge config over the issuer defaults, got service %q", cfg.Service)
+	}
+
+	// Defaults for the other kind of issuer don't apply.
+	cm.AcmeV1().Challenges("team-a").Delete(context.Background(), "www", metav1.DeleteOptions{})
+	cm.AcmeV1().Challenges("team-a").Create(context.Background(), &cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "team-a"},
+		Spec: cmacme.ChallengeSpec{
+			DNSName:   ch.DNSName,
+			Key:       ch.Key,
+			IssuerRef: cmmeta.ObjectReference{Name: "letsencrypt", Kind: "ClusterIssuer"},
+		},
+	}, metav1.CreateOptions{})
+	waitForChallenge(t, challenges, ch.DNSName, ch.Key, func(c *cmacme.Challenge) bool {
+		return c.Spec.IssuerRef.Kind == "ClusterIssuer"
+	})
+	dyn.Resource(nexusIssuerDefaultsResource).Namespace("team-a").Update(context.Background(),
+		issuerDefaultsObject("team-a", "letsencrypt", map[string]interface{}{
+			"issuerKind": "Issuer",
+			"config":     map[string]interface{}{"zoneName": "example.com"},
+		}), metav1.UpdateOptions{})
+	cfg, err = c.challengeConfig(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("challengeConfig: %v", err)
+	}
+	if cfg.ZoneName != "default.com" {
+		t.Errorf("expected Issuer defaults not to apply to a ClusterIssuer, got %+v", cfg)
+	}
+
+	// An issuer without defaults uses the solver's.
+	ch.ResourceNamespace = "team-c"
+	if cfg, err = c.challengeConfig(context.Background(), ch); err != nil || cfg.ZoneName != "default.com" {
+		t.Errorf("expected the solver defaults, got %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,44 @@
+package solver
+
+import (
+	"encoding/base64"
+	"sync"
+)
+
+// keyBuffers holds scratch buffers that API keys are decoded into. Go
+// strings can't be wiped, so the key a provider returns lingers until it is
+// collected, but the decoded copies the solver makes are zeroed as soon as
+// the Nexus client has been built and reused rather than left as garbage.
+var keyBuffers = sync.Pool{New: func() interface{} {
+	buf := make([]byte, 0, 64)
+	return &buf
+}}
+
+// releaseKeyBuffer wipes buf and returns it to keyBuffers.
+func releaseKeyBuffer(buf *[]byte) {
+	clear((*buf)[:cap(*buf)])
+	*buf = (*buf)[:0]
+	keyBuffers.Put(buf)
+}
+
+// decodeBase64Key appends the base64-decoded keyStr to dst, wiping the
+// scratch copy of the encoded key it needs along the way.
+func decodeBase64Key(dst []byte, keyStr string) ([]byte, error) {
+	encoded := []byte(keyStr)
+	defer clear(encoded)
+
+	start := len(dst)
+	n := base64.StdEncoding.DecodedLen(len(encoded))
+	if cap(dst)-start < n {
+		grown := make([]byte, start, start+n)
+		copy(grown, dst)
+		clear(dst)
+		dst = grown
+	}
+	m, err := base64.StdEncoding.Decode(dst[start:start+n], encoded)
+	if err != nil {
+		clear(dst[start : start+n])
+		return nil, err
+	}
+	return dst[:start+m], nil
+}
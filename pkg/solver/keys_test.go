@@ -0,0 +1,27 @@
+package solver
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyBuffers(t *testing.T) {
+	buf := keyBuffers.Get().(*[]byte)
+	key, err := decodeKey((*buf)[:0], "c2VjcmV0", encodingBase64)
+	if err != nil || string(key) != "secret" {
+		t.Fatalf("decodeKey = %q, %v; expected %q", key, err, "secret")
+	}
+	*buf = key
+
+	backing := key[:cap(key)]
+	releaseKeyBuffer(buf)
+	if !bytes.Equal(backing, make([]byte, len(backing))) {
+		t.Errorf("expected the released buffer to be wiped, got %q", backing)
+	}
+
+	long := bytes.Repeat([]byte("k"), 300)
+	key, err = decodeKey([]byte("x"), string(long), encodingPlain)
+	if err != nil || !bytes.Equal(key, append([]byte("x"), long...)) {
+		t.Errorf("expected decodeKey to append to dst, got %q, %v", key, err)
+	}
+}
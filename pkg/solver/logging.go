@@ -0,0 +1,58 @@
+package solver
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/klog/v2"
+)
+
+// jsonSink is a logr.LogSink that writes one JSON object per line.
+// Verbosity follows klog's -v flag, so both formats emit the same lines.
+type jsonSink struct {
+	l *zap.Logger
+}
+
+func newJSONLogger(w zapcore.WriteSyncer) logr.Logger {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(cfg), w, zapcore.DebugLevel)
+	return logr.New(&jsonSink{l: zap.New(core)})
+}
+
+func (j *jsonSink) Init(logr.RuntimeInfo) {}
+
+func (j *jsonSink) Enabled(level int) bool {
+	return klog.V(klog.Level(level)).Enabled()
+}
+
+func (j *jsonSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	j.l.Info(msg, append(zapFields(keysAndValues), zap.Int("v", level))...)
+}
+
+func (j *jsonSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	j.l.Error(msg, append(zapFields(keysAndValues), zap.Error(err))...)
+}
+
+func (j *jsonSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &jsonSink{l: j.l.With(zapFields(keysAndValues)...)}
+}
+
+func (j *jsonSink) WithName(name string) logr.LogSink {
+	return &jsonSink{l: j.l.Named(name)}
+}
+
+func zapFields(keysAndValues []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(keysAndValues)/2+1)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, zap.Any("!BADKEY", key))
+			break
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+	}
+	return fields
+}
@@ -0,0 +1,53 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"k8s.io/klog/v2"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var klogFlags flag.FlagSet
+	klog.InitFlags(&klogFlags)
+	v := klogFlags.Lookup("v").Value
+	prev := v.String()
+	v.Set("2")
+	defer v.Set(prev)
+
+	var buf bytes.Buffer
+	root := newJSONLogger(zapcore.AddSync(&buf)).WithName("nexus")
+
+	root.WithValues("zone", "example.com.").Error(errors.New("boom"), "failed", "record", "_acme-challenge")
+	root.Info("plain")
+	root.V(10).Info("too verbose")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", lines[0], err)
+	}
+	for k, v := range map[string]string{"msg": "failed", "zone": "example.com.", "record": "_acme-challenge", "error": "boom", "logger": "nexus"} {
+		if entry[k] != v {
+			t.Errorf("expected %s=%q, got %v", k, v, entry[k])
+		}
+	}
+
+	// Fields added with WithValues must not leak into the parent logger.
+	entry = map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", lines[1], err)
+	}
+	if _, ok := entry["zone"]; ok {
+		t.Errorf("parent logger picked up child fields: %q", lines[1])
+	}
+}
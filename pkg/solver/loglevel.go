@@ -0,0 +1,54 @@
+package solver
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// klogVerbosity is klog's -v flag. Both log formats follow it, so setting
+// it changes what either one writes.
+var klogVerbosity = sync.OnceValue(func() flag.Value {
+	var fs flag.FlagSet
+	klog.InitFlags(&fs)
+	return fs.Lookup("v").Value
+})
+
+// serveLogLevel reports the log verbosity on GET and sets it on PUT, with
+// the level as the request body, as /debug/flags/v does in Kubernetes
+// components. It lets debug logging be turned on during an incident
+// without restarting the webhook.
+func (p *Process) serveLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 16))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 8)
+		if err != nil {
+			http.Error(w, "log level must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		prev := klogVerbosity().String()
+		if err := klogVerbosity().Set(strconv.FormatUint(level, 10)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.log.Info("log level changed", "from", prev, "to", level)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, klogVerbosity().String())
+}
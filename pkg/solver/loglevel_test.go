@@ -0,0 +1,52 @@
+package solver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeLogLevel(t *testing.T) {
+	prev := klogVerbosity().String()
+	defer klogVerbosity().Set(prev)
+	klogVerbosity().Set("0")
+
+	p := newProcess(DefaultSettings())
+	handler := requireToken("s3cret", http.HandlerFunc(p.serveLogLevel))
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/debug/flags/v", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "4\n"); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "4" {
+		t.Errorf("expected the level to be set to 4, got %d: %q", rec.Code, rec.Body)
+	}
+	if !p.log.V(4).Enabled() || p.log.V(5).Enabled() {
+		t.Errorf("expected debug logging up to level 4")
+	}
+	if rec := do(http.MethodGet, ""); strings.TrimSpace(rec.Body.String()) != "4" {
+		t.Errorf("expected GET to report level 4, got %q", rec.Body)
+	}
+	for _, body := range []string{"-1", "debug", ""} {
+		if rec := do(http.MethodPut, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected level %q to be refused, got %d", body, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, "2"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected POST to be refused, got %d", rec.Code)
+	}
+	if klogVerbosity().String() != "4" {
+		t.Errorf("expected refused requests to leave the level alone, got %s", klogVerbosity())
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/debug/flags/v", strings.NewReader("9"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || klogVerbosity().String() != "4" {
+		t.Errorf("expected a request without a token to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,365 @@
+package solver
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// ActionGenManifests is the subcommand that prints the webhook's manifests.
+const ActionGenManifests = "gen-manifests"
+
+// ManifestOptions parameterizes the manifests GenManifests prints.
+type ManifestOptions struct {
+	Name      string
+	Namespace string
+	GroupName string
+	Image     string
+
+	CertManagerNamespace      string
+	CertManagerServiceAccount string
+	// SecretNamespaces are the namespaces API key Secrets are read from,
+	// besides CertManagerNamespace, where ClusterIssuers keep theirs.
+	SecretNamespaces []string
+
+	StateConfigMap string
+	StateCRD       bool
+	EmitEvents     bool
+	SecretInformer bool
+}
+
+// GenManifests prints, as a YAML stream, the ServiceAccount, RBAC, serving
+// certificate, Service, Deployment and APIService a webhook needs to run.
+// The permissions come from the same definitions the solver uses, so they
+// track what it does rather than a hand-kept copy.
+func GenManifests(args []string, w io.Writer) error {
+	image := "fudoniten/cert-manager-webhook-nexus:latest"
+	if version != "dev" {
+		image = "fudoniten/cert-manager-webhook-nexus:" + version
+	}
+
+	o := ManifestOptions{}
+	fs := flag.NewFlagSet(ActionGenManifests, flag.ExitOnError)
+	fs.StringVar(&o.Name, "name", "cert-manager-webhook-nexus", "Name of the webhook's resources.")
+	fs.StringVar(&o.Namespace, "namespace", "cert-manager", "Namespace to deploy the webhook in.")
+	fs.StringVar(&o.GroupName, "group-name", os.Getenv("GROUP_NAME"), "API group the solvers are served under. Defaults to $GROUP_NAME.")
+	fs.StringVar(&o.Image, "image", image, "Webhook image.")
+	fs.StringVar(&o.CertManagerNamespace, "cert-manager-namespace", "cert-manager", "Namespace cert-manager runs in.")
+	fs.StringVar(&o.CertManagerServiceAccount, "cert-manager-service-account", "cert-manager", "Service account cert-manager calls the webhook as.")
+	secretNamespaces := fs.String("secret-namespaces", "", "Comma-separated extra namespaces API key Secrets are read from.")
+	fs.StringVar(&o.StateConfigMap, "state-configmap", "", "ConfigMap to keep challenge state in, as for the webhook.")
+	fs.BoolVar(&o.StateCRD, "state-crd", false, "Keep challenge state in NexusChallenge resources; the CRD is in the chart's crds directory.")
+	fs.BoolVar(&o.EmitEvents, "emit-events", true, "Grant what recording failures as Events on Challenges needs.")
+	fs.BoolVar(&o.SecretInformer, "secret-informer", true, "Grant what caching Secrets with an informer needs.")
+	fs.Parse(args)
+	for _, ns := range strings.Split(*secretNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			o.SecretNamespaces = append(o.SecretNamespaces, ns)
+		}
+	}
+
+	objects, err := Manifests(o)
+	if err != nil {
+		return err
+	}
+	for i, obj := range objects {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		if _, err = w.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Manifests returns the objects GenManifests prints for o.
+func Manifests(o ManifestOptions) ([]runtime.Object, error) {
+	if o.GroupName == "" {
+		return nil, errors.New("--group-name (or $GROUP_NAME) is required")
+	}
+	if errs := validation.IsDNS1123Subdomain(o.GroupName); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid API group %q: %s", o.GroupName, strings.Join(errs, "; "))
+	}
+	if o.StateConfigMap != "" && o.StateCRD {
+		return nil, errors.New("--state-configmap and --state-crd can't both be set")
+	}
+
+	m := manifestBuilder{o: o}
+	m.add(&corev1.ServiceAccount{TypeMeta: typeMeta("v1", "ServiceAccount"), ObjectMeta: m.meta(o.Name, o.Namespace)})
+
+	// What every aggregated API server needs: reading the request header
+	// CA, delegating authn/authz, and following priority and fairness.
+	m.bind(o.Name+":webhook-authentication-reader", "kube-system", "Role", "extension-apiserver-authentication-reader")
+	m.bind(o.Name+":auth-delegator", "", "ClusterRole", "system:auth-delegator")
+	m.grant(o.Name+":flowcontrol", "", rbacv1.PolicyRule{
+		APIGroups: []string{"flowcontrol.apiserver.k8s.io"},
+		Resources: []string{"prioritylevelconfigurations", "flowschemas"},
+		Verbs:     []string{"list", "watch"},
+	})
+
+	// cert-manager calls the solvers.
+	m.add(&rbacv1.ClusterRole{
+		TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "ClusterRole"),
+		ObjectMeta: m.meta(o.Name+":domain-solver", ""),
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{o.GroupName}, Resources: []string{"*"}, Verbs: []string{"create"}}},
+	})
+	m.add(&rbacv1.ClusterRoleBinding{
+		TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "ClusterRoleBinding"),
+		ObjectMeta: m.meta(o.Name+":domain-solver", ""),
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: o.Name + ":domain-solver"},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      o.CertManagerServiceAccount,
+			Namespace: o.CertManagerNamespace,
+		}},
+	})
+
+	secrets := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: secretVerbs(o.SecretInformer)}
+	seen := map[string]bool{}
+	for _, ns := range append([]string{o.CertManagerNamespace}, o.SecretNamespaces...) {
+		if !seen[ns] {
+			seen[ns] = true
+			m.grant(o.Name+":secret-reader", ns, secrets)
+		}
+	}
+
+	switch {
+	case o.StateCRD:
+		m.grant(o.Name+":state", o.Namespace, rbacv1.PolicyRule{
+			APIGroups: []string{nexusChallengeResource.Group},
+			Resources: []string{nexusChallengeResource.Resource},
+			Verbs:     crdStoreVerbs,
+		})
+	case o.StateConfigMap != "":
+		// Create can't be limited by name, so neither is the rest.
+		m.grant(o.Name+":state", o.Namespace, rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     configMapStoreVerbs,
+		})
+	}
+	if o.EmitEvents {
+		m.grant(o.Name+":challenges", "",
+			rbacv1.PolicyRule{APIGroups: []string{cmacme.SchemeGroupVersion.Group}, Resources: []string{"challenges"}, Verbs: []string{"list", "watch"}},
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+		)
+	}
+
+	m.servingCertificate()
+	m.service()
+	m.deployment()
+	m.apiService()
+	return m.objects, nil
+}
+
+// secretVerbs are the verbs the solver reads Secrets with: get, plus list
+// and watch for the informer, which watches whole namespaces.
+func secretVerbs(informer bool) []string {
+	if informer {
+		return []string{"get", "list", "watch"}
+	}
+	return []string{"get"}
+}
+
+type manifestBuilder struct {
+	o       ManifestOptions
+	objects []runtime.Object
+}
+
+func (m *manifestBuilder) add(obj runtime.Object) {
+	m.objects = append(m.objects, obj)
+}
+
+func (m *manifestBuilder) meta(name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": m.o.Name}}
+}
+
+func (m *manifestBuilder) tlsSecret() string { return m.o.Name + "-webhook-tls" }
+
+func typeMeta(apiVersion, kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{APIVersion: apiVersion, Kind: kind}
+}
+
+// bind binds the webhook's service account to an existing role, in
+// namespace, or cluster-wide if namespace is empty.
+func (m *manifestBuilder) bind(name, namespace, kind, role string) {
+	ref := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: kind, Name: role}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: m.o.Name, Namespace: m.o.Namespace}}
+	if namespace == "" {
+		m.add(&rbacv1.ClusterRoleBinding{
+			TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "ClusterRoleBinding"),
+			ObjectMeta: m.meta(name, ""),
+			RoleRef:    ref,
+			Subjects:   subjects,
+		})
+		return
+	}
+	m.add(&rbacv1.RoleBinding{
+		TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "RoleBinding"),
+		ObjectMeta: m.meta(name, namespace),
+		RoleRef:    ref,
+		Subjects:   subjects,
+	})
+}
+
+// grant creates a role with rules and binds the webhook to it, in
+// namespace, or cluster-wide if namespace is empty.
+func (m *manifestBuilder) grant(name, namespace string, rules ...rbacv1.PolicyRule) {
+	if namespace == "" {
+		m.add(&rbacv1.ClusterRole{
+			TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "ClusterRole"),
+			ObjectMeta: m.meta(name, ""),
+			Rules:      rules,
+		})
+		m.bind(name, "", "ClusterRole", name)
+		return
+	}
+	m.add(&rbacv1.Role{
+		TypeMeta:   typeMeta("rbac.authorization.k8s.io/v1", "Role"),
+		ObjectMeta: m.meta(name, namespace),
+		Rules:      rules,
+	})
+	m.bind(name, namespace, "Role", name)
+}
+
+// servingCertificate has cert-manager issue the webhook a self-signed
+// serving certificate, which is also the CA injected into the APIService.
+func (m *manifestBuilder) servingCertificate() {
+	o := m.o
+	issuer := o.Name + "-selfsign"
+	m.add(&cmapi.Issuer{
+		TypeMeta:   typeMeta(cmapi.SchemeGroupVersion.String(), cmapi.IssuerKind),
+		ObjectMeta: m.meta(issuer, o.Namespace),
+		Spec:       cmapi.IssuerSpec{IssuerConfig: cmapi.IssuerConfig{SelfSigned: &cmapi.SelfSignedIssuer{}}},
+	})
+	m.add(&cmapi.Certificate{
+		TypeMeta:   typeMeta(cmapi.SchemeGroupVersion.String(), cmapi.CertificateKind),
+		ObjectMeta: m.meta(m.tlsSecret(), o.Namespace),
+		Spec: cmapi.CertificateSpec{
+			SecretName: m.tlsSecret(),
+			Duration:   &metav1.Duration{Duration: 8760 * time.Hour}, // 1y
+			IssuerRef:  cmmeta.ObjectReference{Name: issuer},
+			DNSNames:   []string{o.Name, o.Name + "." + o.Namespace, o.Name + "." + o.Namespace + ".svc"},
+		},
+	})
+}
+
+func (m *manifestBuilder) service() {
+	m.add(&corev1.Service{
+		TypeMeta:   typeMeta("v1", "Service"),
+		ObjectMeta: m.meta(m.o.Name, m.o.Namespace),
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{"app": m.o.Name},
+			Ports:    []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromString("https"), Protocol: corev1.ProtocolTCP}},
+		},
+	})
+}
+
+func (m *manifestBuilder) deployment() {
+	o := m.o
+	args := []string{
+		"--secure-port=443",
+		"--tls-cert-file=/tls/tls.crt",
+		"--tls-private-key-file=/tls/tls.key",
+		fmt.Sprintf("--emit-events=%t", o.EmitEvents),
+		fmt.Sprintf("--secret-informer=%t", o.SecretInformer),
+		"--rbac-preflight-namespaces=" + o.CertManagerNamespace,
+	}
+	if len(o.SecretNamespaces) > 0 {
+		args = append(args, "--allowed-secret-namespaces="+strings.Join(o.SecretNamespaces, ","))
+	}
+	switch {
+	case o.StateCRD:
+		args = append(args, "--state-crd")
+	case o.StateConfigMap != "":
+		args = append(args, "--state-configmap="+o.StateConfigMap)
+	}
+
+	replicas := int32(1)
+	labels := map[string]string{"app": o.Name}
+	probe := func(path string) *corev1.Probe {
+		return &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromString("health")}}}
+	}
+	m.add(&appsv1.Deployment{
+		TypeMeta:   typeMeta("apps/v1", "Deployment"),
+		ObjectMeta: m.meta(o.Name, o.Namespace),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: o.Name,
+					Containers: []corev1.Container{{
+						Name:  "webhook",
+						Image: o.Image,
+						Args:  args,
+						Env: []corev1.EnvVar{
+							{Name: "GROUP_NAME", Value: o.GroupName},
+							{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+						},
+						Ports: []corev1.ContainerPort{
+							{Name: "https", ContainerPort: 443, Protocol: corev1.ProtocolTCP},
+							{Name: "health", ContainerPort: 6080, Protocol: corev1.ProtocolTCP},
+						},
+						LivenessProbe:  probe("/healthz"),
+						ReadinessProbe: probe("/readyz"),
+						VolumeMounts:   []corev1.VolumeMount{{Name: "certs", MountPath: "/tls", ReadOnly: true}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "certs",
+						VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: m.tlsSecret()}},
+					}},
+				},
+			},
+		},
+	})
+}
+
+// apiService registers the solvers' API group. It's unstructured since
+// the aggregator's types aren't otherwise a dependency.
+func (m *manifestBuilder) apiService() {
+	o := m.o
+	m.add(&unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiregistration.k8s.io/v1",
+		"kind":       "APIService",
+		"metadata": map[string]interface{}{
+			"name":   "v1alpha1." + o.GroupName,
+			"labels": map[string]interface{}{"app": o.Name},
+			"annotations": map[string]interface{}{
+				"cert-manager.io/inject-ca-from": o.Namespace + "/" + m.tlsSecret(),
+			},
+		},
+		"spec": map[string]interface{}{
+			"group":                o.GroupName,
+			"version":              "v1alpha1",
+			"groupPriorityMinimum": int64(1000),
+			"versionPriority":      int64(15),
+			"service":              map[string]interface{}{"name": o.Name, "namespace": o.Namespace},
+		},
+	}})
+}
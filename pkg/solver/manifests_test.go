@@ -0,0 +1,74 @@
+package solver
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestManifests(t *testing.T) {
+	objects, err := Manifests(ManifestOptions{
+		Name:                      "nexus",
+		Namespace:                 "dns",
+		GroupName:                 "acme.example.com",
+		Image:                     "nexus:test",
+		CertManagerNamespace:      "cert-manager",
+		CertManagerServiceAccount: "cert-manager",
+		SecretNamespaces:          []string{"shared", "cert-manager"},
+		StateCRD:                  true,
+		SecretInformer:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var secretRoles []string
+	var state *rbacv1.Role
+	var deployment *appsv1.Deployment
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *rbacv1.Role:
+			switch obj.Name {
+			case "nexus:secret-reader":
+				secretRoles = append(secretRoles, obj.Namespace)
+			case "nexus:state":
+				state = obj
+			}
+		case *appsv1.Deployment:
+			deployment = obj
+		}
+	}
+	if !slices.Equal(secretRoles, []string{"cert-manager", "shared"}) {
+		t.Errorf("expected Secret access in cert-manager and shared once each, got %v", secretRoles)
+	}
+	if state == nil || !slices.Equal(state.Rules[0].Verbs, crdStoreVerbs) {
+		t.Errorf("expected the state role to grant the CRD store's verbs, got %+v", state)
+	}
+	if deployment == nil {
+		t.Fatal("expected a Deployment")
+	}
+	args := deployment.Spec.Template.Spec.Containers[0].Args
+	if !slices.Contains(args, "--state-crd") || !slices.Contains(args, "--allowed-secret-namespaces=shared,cert-manager") {
+		t.Errorf("expected the Deployment to match the options, got args %q", args)
+	}
+
+	if _, err = Manifests(ManifestOptions{Name: "nexus"}); err == nil {
+		t.Error("expected an error without a group name")
+	}
+}
+
+func TestGenManifests(t *testing.T) {
+	var out bytes.Buffer
+	if err := GenManifests([]string{"--group-name=acme.example.com"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: APIService", "name: v1alpha1.acme.example.com", "kind: Deployment", "- --secure-port=443"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q", want)
+		}
+	}
+}
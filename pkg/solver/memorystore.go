@@ -0,0 +1,63 @@
+package solver
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// memoryStore keeps challenges in memory. It doesn't survive a restart,
+// but solvers in one process can share it.
+type memoryStore struct {
+	lock    sync.Mutex
+	entries map[ChallengeKey]StoredChallenge
+}
+
+// NewMemoryStateStore returns a StateStore that keeps challenges in memory,
+// for use with WithStateStore.
+func NewMemoryStateStore() StateStore {
+	return &memoryStore{entries: map[ChallengeKey]StoredChallenge{}}
+}
+
+func (s *memoryStore) Get(_ context.Context, ck ChallengeKey) (StoredChallenge, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sc, ok := s.entries[ck]
+	return sc, ok, nil
+}
+
+// List returns every stored challenge, ordered by when it was presented.
+func (s *memoryStore) List(context.Context) ([]StoredChallenge, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stored := make([]StoredChallenge, 0, len(s.entries))
+	for _, sc := range s.entries {
+		stored = append(stored, sc)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].PresentedAt.Before(stored[j].PresentedAt) })
+	return stored, nil
+}
+
+func (s *memoryStore) Put(_ context.Context, ck ChallengeKey, sc StoredChallenge) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries[ck] = sc
+	return nil
+}
+
+func (s *memoryStore) Claim(_ context.Context, ck ChallengeKey, sc StoredChallenge) (StoredChallenge, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if held, ok := s.entries[ck]; ok {
+		return held, nil
+	}
+	s.entries[ck] = sc
+	return sc, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, ck ChallengeKey) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.entries, ck)
+	return nil
+}
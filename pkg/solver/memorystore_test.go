@@ -0,0 +1,60 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStateStore()
+	ctx := context.Background()
+	ck := ChallengeKey{fqdn: "_acme-challenge.example.com.", key: "token"}
+
+	id := uuid.New()
+	if held, err := store.Claim(ctx, ck, StoredChallenge{ID: id, PresentedAt: time.Now()}); err != nil || held.ID != id {
+		t.Fatalf("expected the first claim to win, got %+v, %v", held, err)
+	}
+	if held, err := store.Claim(ctx, ck, StoredChallenge{ID: uuid.New()}); err != nil || held.ID != id {
+		t.Errorf("expected claim to return the existing entry %s, got %+v, %v", id, held, err)
+	}
+	older := uuid.New()
+	if err := store.Put(ctx, ChallengeKey{fqdn: ck.fqdn, key: "other"}, StoredChallenge{ID: older, PresentedAt: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	stored, err := store.List(ctx)
+	if err != nil || len(stored) != 2 || stored[0].ID != older || stored[1].ID != id {
+		t.Fatalf("expected both challenges, oldest first, got %v, %v", stored, err)
+	}
+
+	if err := store.Delete(ctx, ck); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok, err := store.Get(ctx, ck); err != nil || ok {
+		t.Fatalf("expected challenge to be deleted, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestWithStateStore(t *testing.T) {
+	c, _, ch := newTestSolver(t, "")
+	store := NewMemoryStateStore()
+	WithStateStore(store)(c)
+
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if _, ok, err := store.Get(context.Background(), newChallengeKey(ch)); err != nil || !ok {
+		t.Fatalf("expected the challenge in the plugged-in store, got ok=%v err=%v", ok, err)
+	}
+	// Forget the in-memory state, as another solver sharing the store.
+	c.challenges = nil
+	if err := c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if stored, err := store.List(context.Background()); err != nil || len(stored) != 0 {
+		t.Errorf("expected CleanUp to delete the entry, got %v, %v", stored, err)
+	}
+}
@@ -0,0 +1,220 @@
+package solver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "nexus_webhook"
+
+const (
+	opPresent = "present"
+	opCleanUp = "cleanup"
+)
+
+// Nexus endpoints, as the endpoint label of the nexus_api_* metrics.
+const (
+	endpointCreate = "CreateChallengeRecord"
+	endpointDelete = "DeleteChallengeRecord"
+)
+
+var (
+	operationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "operations_total",
+		Help:      "Present and CleanUp calls handled, by operation and result.",
+	}, []string{"operation", "result"})
+
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "operation_duration_seconds",
+		Help:      "Time taken to handle Present and CleanUp calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	nexusErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "nexus_api_errors_total",
+		Help:      "Errors returned by the Nexus API, by operation.",
+	}, []string{"operation"})
+
+	nexusRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "nexus_api_requests_total",
+		Help:      "Nexus API calls made, retries included, by endpoint, zone and status.",
+	}, []string{"endpoint", "zone", "status"})
+
+	nexusRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "nexus_api_request_duration_seconds",
+		Help:      "Latency of Nexus API calls, retries counted separately, by endpoint, zone and status.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 13),
+	}, []string{"endpoint", "zone", "status"})
+
+	secretFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "secret_lookup_failures_total",
+		Help:      "Failures to read or decode the Nexus API key, by reason.",
+	}, []string{"reason"})
+
+	clientCacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "client_cache_lookups_total",
+		Help:      "Nexus client cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	zoneCacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "zone_cache_lookups_total",
+		Help:      "Zone cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	challengeLifetime = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "challenge_lifetime_seconds",
+		Help:      "Time between presenting a challenge record and cleaning it up.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	orphansCollectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_records_deleted_total",
+		Help:      "Records deleted by the garbage collector because their Challenge no longer exists.",
+	})
+
+	failoversTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "fallback_records_created_total",
+		Help:      "Records created in an issuer's fallback service after the primary one failed.",
+	})
+
+	recordsRecreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "records_recreated_total",
+		Help:      "Presented records the verifier found missing from DNS and created again.",
+	})
+)
+
+// Reasons for secret_lookup_failures_total.
+const (
+	secretReasonNotFound   = "secret_not_found"
+	secretReasonKeyMissing = "key_not_found"
+	secretReasonForbidden  = "forbidden"
+	secretReasonDecode     = "decode"
+	secretReasonProvider   = "provider_error"
+)
+
+// secretFailureReason classifies an error from fetching an API key.
+func secretFailureReason(err error) string {
+	switch {
+	case apierrors.IsForbidden(err):
+		return secretReasonForbidden
+	case apierrors.IsNotFound(err):
+		return secretReasonNotFound
+	case errors.Is(err, ErrSecretNotFound):
+		// The Secret exists but has no such key, or none matched the
+		// selector with it.
+		return secretReasonKeyMissing
+	default:
+		return secretReasonProvider
+	}
+}
+
+// observeOperation records the outcome and duration of a Present or CleanUp
+// call that started at start.
+func observeOperation(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	operationsTotal.WithLabelValues(operation, result).Inc()
+	operationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// observeNexusCall records the latency and status of one Nexus API call to
+// endpoint for zone that started at start.
+func observeNexusCall(endpoint, zone string, start time.Time, err error) {
+	status := nexusStatus(err)
+	nexusRequestsTotal.WithLabelValues(endpoint, zone, status).Inc()
+	nexusRequestDuration.WithLabelValues(endpoint, zone, status).Observe(time.Since(start).Seconds())
+}
+
+// nexusStatus labels the outcome of a Nexus call: "ok", the HTTP status
+// code if the error carries one, "timeout", or "error".
+func nexusStatus(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if code, ok := statusCode(err); ok {
+		return strconv.Itoa(code)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return "error"
+}
+
+// startMetricsServer serves /metrics and /version on addr until stopCh is
+// closed.
+func (p *Process) startMetricsServer(addr string, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/version", serveVersion)
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	if p.settings.MetricsCertFile != "" {
+		srv.TLSConfig = &tls.Config{GetCertificate: p.settings.metricsCertificate}
+	}
+	go func() {
+		<-stopCh
+		srv.Close()
+	}()
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			p.log.Error(err, "metrics server failed", "address", addr)
+		}
+	}()
+}
+
+// checkMetricsTLS reports a metrics certificate without its key, or one
+// that can't be loaded, at startup rather than on the first scrape.
+func (s *Settings) checkMetricsTLS() error {
+	if (s.MetricsCertFile == "") != (s.MetricsKeyFile == "") {
+		return errors.New("--metrics-tls-cert-file and --metrics-tls-private-key-file must be set together")
+	}
+	if s.MetricsCertFile == "" {
+		return nil
+	}
+	if _, err := s.metricsCertificate(nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// metricsCertificate reads the metrics certificate on each handshake, so a
+// rotated one is picked up without a restart; scrapes are rare enough that
+// caching it isn't worth the staleness.
+func (s *Settings) metricsCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(s.MetricsCertFile, s.MetricsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load metrics certificate: %w", err)
+	}
+	return &cert, nil
+}
@@ -0,0 +1,124 @@
+package solver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+func TestNexusCallMetrics(t *testing.T) {
+	tests := []struct {
+		err    error
+		status string
+	}{
+		{nil, "ok"},
+		{errors.New("failed to create record: 503 Service Unavailable"), "503"},
+		{fmt.Errorf("nexus call abandoned: %w", context.DeadlineExceeded), "timeout"},
+		{errors.New("connection refused"), "error"},
+	}
+	for _, test := range tests {
+		if got := nexusStatus(test.err); got != test.status {
+			t.Errorf("nexusStatus(%v) = %q, expected %q", test.err, got, test.status)
+		}
+	}
+
+	counter := nexusRequestsTotal.WithLabelValues(endpointCreate, "metrics.example.com", "503")
+	before := testutil.ToFloat64(counter)
+	observeNexusCall(endpointCreate, "metrics.example.com", time.Now(), errors.New("503 Service Unavailable"))
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("expected one request counted, got %v", got)
+	}
+}
+
+func TestSecretFailureReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{fmt.Errorf("%w: %w", ErrSecretNotFound, apierrors.NewNotFound(corev1.Resource("secrets"), "nexus")), secretReasonNotFound},
+		{fmt.Errorf("%w: secret default/nexus has no key %q", ErrSecretNotFound, "key"), secretReasonKeyMissing},
+		{fmt.Errorf("failed to get secret: %w", apierrors.NewForbidden(corev1.Resource("secrets"), "nexus", errors.New("no RBAC"))), secretReasonForbidden},
+		{errors.New("vault: permission denied"), secretReasonProvider},
+	}
+	for _, test := range tests {
+		if got := secretFailureReason(test.err); got != test.reason {
+			t.Errorf("secretFailureReason(%v) = %q, expected %q", test.err, got, test.reason)
+		}
+	}
+}
+
+func TestClientCacheMetrics(t *testing.T) {
+	hits, misses := clientCacheLookupsTotal.WithLabelValues("hit"), clientCacheLookupsTotal.WithLabelValues("miss")
+	hitsBefore, missesBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses)
+
+	cc := clientCache{ttl: time.Hour}
+	build := func() (nexusclient.API, error) { return nil, nil }
+	for i := 0; i < 3; i++ {
+		if _, err := cc.get(nexusclient.V1, "example.com", "svc", []byte("key"), build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := testutil.ToFloat64(misses) - missesBefore; got != 1 {
+		t.Errorf("expected one miss, got %v", got)
+	}
+	if got := testutil.ToFloat64(hits) - hitsBefore; got != 2 {
+		t.Errorf("expected two hits, got %v", got)
+	}
+}
+
+func TestCheckMetricsTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cert, key string
+		ok        bool
+	}{
+		{"", "", true},
+		{certPath, keyPath, true},
+		{certPath, "", false},
+		{certPath, filepath.Join(dir, "missing.key"), false},
+	}
+	for _, test := range tests {
+		s := Settings{MetricsCertFile: test.cert, MetricsKeyFile: test.key}
+		if err := s.checkMetricsTLS(); (err == nil) != test.ok {
+			t.Errorf("checkMetricsTLS() with cert %q, key %q = %v", test.cert, test.key, err)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package solver
+
+import "github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+
+// apiVersion returns the configured Nexus API version, defaulting to v1.
+func (cfg *Config) apiVersion() string {
+	if cfg.APIVersion == "" {
+		return nexusclient.V1
+	}
+	return cfg.APIVersion
+}
@@ -0,0 +1,41 @@
+package solver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// checkZonePolicy refuses records outside the zones the operator allows
+// with AllowedZones and DeniedZones, and those an issuer allows with
+// allowedZones, so a compromised Issuer can't get records created in zones
+// it shouldn't touch.
+func (p *Process) checkZonePolicy(fqdn string, cfg *Config) error {
+	if zone, ok := matchZone(fqdn, p.settings.DeniedZones); ok {
+		return fmt.Errorf("%w: record %s is in denied zone %s", ErrZoneNotAllowed, fqdn, zone)
+	}
+	if zones := p.settings.AllowedZones; len(zones) > 0 {
+		if _, ok := matchZone(fqdn, zones); !ok {
+			return fmt.Errorf("%w: record %s is not in --allowed-zones", ErrZoneNotAllowed, fqdn)
+		}
+	}
+	if len(cfg.AllowedZones) > 0 {
+		if _, ok := matchZone(fqdn, cfg.AllowedZones); !ok {
+			return fmt.Errorf("%w: record %s is not in the issuer's allowedZones", ErrZoneNotAllowed, fqdn)
+		}
+	}
+	return nil
+}
+
+// matchZone returns the first of zones that fqdn is in or equal to.
+func matchZone(fqdn string, zones []string) (string, bool) {
+	name := strings.ToLower(util.ToFqdn(fqdn))
+	for _, zone := range zones {
+		z := strings.ToLower(util.ToFqdn(zone))
+		if name == z || strings.HasSuffix(name, "."+z) {
+			return zone, true
+		}
+	}
+	return "", false
+}
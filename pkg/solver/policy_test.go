@@ -0,0 +1,32 @@
+package solver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckZonePolicy(t *testing.T) {
+	s := DefaultSettings()
+	s.AllowedZones = []string{"example.com", "example.net."}
+	s.DeniedZones = []string{"internal.example.com"}
+	p := newProcess(s)
+
+	tests := []struct {
+		fqdn    string
+		issuer  []string
+		allowed bool
+	}{
+		{fqdn: "_acme-challenge.www.example.com.", allowed: true},
+		{fqdn: "_acme-challenge.EXAMPLE.net.", allowed: true},
+		{fqdn: "_acme-challenge.notexample.com.", allowed: false},
+		{fqdn: "_acme-challenge.host.internal.example.com.", allowed: false},
+		{fqdn: "_acme-challenge.www.example.com.", issuer: []string{"example.net"}, allowed: false},
+		{fqdn: "_acme-challenge.www.example.net.", issuer: []string{"example.net"}, allowed: true},
+	}
+	for _, test := range tests {
+		err := p.checkZonePolicy(test.fqdn, &Config{AllowedZones: test.issuer})
+		if (err == nil) != test.allowed || (err != nil && !errors.Is(err, ErrZoneNotAllowed)) {
+			t.Errorf("checkZonePolicy(%q, %v) = %v, expected allowed=%v", test.fqdn, test.issuer, err, test.allowed)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package solver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// pprofListenAddress checks that addr only listens on loopback, since the
+// profiles expose memory contents and aren't authenticated. A bare port
+// listens on 127.0.0.1.
+func pprofListenAddress(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid --pprof-bind-address: %w", err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("--pprof-bind-address must be a loopback address, got %q", host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// startPprofServer serves the pprof handlers on addr until stopCh is closed.
+func (p *Process) startPprofServer(addr string, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-stopCh
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			p.log.Error(err, "pprof server failed", "address", addr)
+		}
+	}()
+}
@@ -0,0 +1,29 @@
+package solver
+
+import "testing"
+
+func TestPprofListenAddress(t *testing.T) {
+	tests := []struct {
+		addr, expected string
+		fails          bool
+	}{
+		{addr: ":6060", expected: "127.0.0.1:6060"},
+		{addr: "localhost:6060", expected: "localhost:6060"},
+		{addr: "[::1]:6060", expected: "[::1]:6060"},
+		{addr: "0.0.0.0:6060", fails: true},
+		{addr: "10.0.0.1:6060", fails: true},
+		{addr: "6060", fails: true},
+	}
+	for _, test := range tests {
+		addr, err := pprofListenAddress(test.addr)
+		if test.fails {
+			if err == nil {
+				t.Errorf("pprofListenAddress(%q): expected an error", test.addr)
+			}
+			continue
+		}
+		if err != nil || addr != test.expected {
+			t.Errorf("pprofListenAddress(%q) = %q, %v; expected %q", test.addr, addr, err, test.expected)
+		}
+	}
+}
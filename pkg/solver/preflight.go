@@ -0,0 +1,82 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// preflight checks the webhook can read Secrets in every namespace it's
+// known to need, so missing RBAC is reported at startup rather than as a
+// bare forbidden error on the first challenge. Problems are only logged
+// unless RBACPreflightStrict is set. It runs once per process, however
+// many solvers it serves.
+func (p *Process) preflight(ctx context.Context, client kubernetes.Interface) error {
+	namespaces := p.settings.preflightNamespaces()
+	if len(namespaces) == 0 {
+		return nil
+	}
+	missing, err := p.checkSecretAccess(ctx, client, namespaces)
+	if err != nil {
+		// The review itself failing says nothing about the
+		// permissions, so never block startup on it.
+		p.log.Error(err, "could not run RBAC preflight")
+		return nil
+	}
+	if len(missing) == 0 {
+		p.log.Info("RBAC preflight passed", "namespaces", namespaces)
+		return nil
+	}
+	err = fmt.Errorf("webhook service account can't %s; challenges with API keys there will fail", strings.Join(missing, ", "))
+	p.log.Error(err, "RBAC preflight found missing permissions")
+	if p.settings.RBACPreflightStrict {
+		return err
+	}
+	return nil
+}
+
+func (s *Settings) preflightNamespaces() []string {
+	seen := map[string]bool{}
+	var namespaces []string
+	for _, list := range [][]string{s.RBACPreflightNamespaces, s.AllowedSecretNamespaces} {
+		for _, ns := range list {
+			if !seen[ns] {
+				seen[ns] = true
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// checkSecretAccess asks the API server which of the Secret permissions
+// the webhook uses it lacks in namespaces.
+func (p *Process) checkSecretAccess(ctx context.Context, client kubernetes.Interface, namespaces []string) (missing []string, err error) {
+	verbs := secretVerbs(p.settings.SecretInformer)
+	for _, ns := range namespaces {
+		for _, verb := range verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{Namespace: ns, Verb: verb, Resource: "secrets"},
+				},
+			}
+			reviewCtx, cancel := p.withKubeTimeout(ctx)
+			review, err = client.AuthorizationV1().SelfSubjectAccessReviews().Create(reviewCtx, review, metav1.CreateOptions{})
+			cancel()
+			if err != nil {
+				err = fmt.Errorf("access review for %s secrets in %s: %w", verb, ns, err)
+				return
+			}
+			if !review.Status.Allowed {
+				missing = append(missing, fmt.Sprintf("%s secrets in %s", verb, ns))
+			}
+		}
+	}
+	return
+}
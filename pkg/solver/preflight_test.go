@@ -0,0 +1,41 @@
+package solver
+
+import (
+	"context"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckSecretAccess(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	kube.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Namespace == "cert-manager" || attrs.Verb == "get"
+		return true, review, nil
+	})
+
+	missing, err := newProcess(DefaultSettings()).checkSecretAccess(context.Background(), kube, []string{"cert-manager", "shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"list secrets in shared", "watch secrets in shared"}
+	if len(missing) != len(expected) || missing[0] != expected[0] || missing[1] != expected[1] {
+		t.Errorf("expected %v to be missing, got %v", expected, missing)
+	}
+}
+
+func TestPreflightNamespaces(t *testing.T) {
+	s := Settings{
+		RBACPreflightNamespaces: []string{"cert-manager", "shared"},
+		AllowedSecretNamespaces: []string{"shared", "dns"},
+	}
+	namespaces := s.preflightNamespaces()
+	if len(namespaces) != 3 || namespaces[0] != "cert-manager" || namespaces[1] != "dns" || namespaces[2] != "shared" {
+		t.Errorf("expected the namespaces deduplicated and sorted, got %v", namespaces)
+	}
+}
@@ -0,0 +1,166 @@
+package solver
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+)
+
+// Process holds what the solvers served by one webhook share: its
+// settings, logger, audit log, Nexus rate limits and caches, and the
+// metrics, health and debug servers. Solvers built without WithProcess
+// get one of their own with DefaultSettings.
+type Process struct {
+	settings Settings
+	log      logr.Logger
+
+	audit   auditLog
+	health  healthServer
+	cluster clusterDefaults
+	tenants tenantPolicies
+	zones   zoneCache
+	streaks failureStreaks
+
+	// limiter is nil, and slots too, if there's no limit.
+	limiter flowcontrol.RateLimiter
+	slots   chan struct{}
+
+	// challenges is started by the first solver that looks Challenges up.
+	challengesOnce sync.Once
+	challenges     *challengeLister
+	challengesErr  error
+
+	// setup guards the parts of Initialize that only run once per
+	// process, however many solvers it serves.
+	setup    sync.Once
+	setupErr error
+
+	lock    sync.Mutex
+	solvers []*Solver
+}
+
+// NewProcess checks s and returns a Process for solvers to share through
+// WithProcess.
+func NewProcess(s Settings) (*Process, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return newProcess(s), nil
+}
+
+func newProcess(s Settings) *Process {
+	p := &Process{settings: s}
+	if s.LogFormat == "json" {
+		p.setLogger(newJSONLogger(os.Stdout).WithName("nexus"))
+	} else {
+		p.setLogger(klog.NewKlogr().WithName("nexus"))
+	}
+	p.health.readinessURL = s.ReadinessNexusURL
+	p.zones.ttl = s.ZoneCacheTTL
+	p.zones.negativeTTL = s.ZoneCacheNegativeTTL
+	p.zones.size = s.ZoneCacheSize
+	p.zones.family = p.family()
+	p.streaks.warnEvery = s.FailureStreakWarning
+	if s.NexusQPS > 0 {
+		p.limiter = flowcontrol.NewTokenBucketRateLimiter(float32(s.NexusQPS), s.NexusBurst)
+	}
+	if s.MaxConcurrentChallenges > 0 {
+		p.slots = make(chan struct{}, s.MaxConcurrentChallenges)
+	}
+	return p
+}
+
+// setLogger makes p log to l.
+func (p *Process) setLogger(l logr.Logger) {
+	p.log = l
+	p.audit.log = l.WithName("audit")
+	p.health.log = l
+	p.cluster.log = l
+	p.tenants.log = l
+}
+
+// WithProcess makes the solver share p with the other solvers built with
+// it, rather than getting a Process of its own.
+func WithProcess(p *Process) Option {
+	return func(c *Solver) { c.proc = p }
+}
+
+// family is the address family nameservers are preferably reached over.
+func (p *Process) family() ipFamily {
+	return ipFamily(p.settings.PreferIPFamily)
+}
+
+// start runs the parts of Initialize every solver in the process shares:
+// the RBAC preflight, tenant policy, audit log, tracing, cluster defaults,
+// SIGHUP handling and the metrics, health, pprof and debug servers. Only
+// the first call does anything; later ones return its error.
+func (p *Process) start(client kubernetes.Interface, stopCh <-chan struct{}) error {
+	p.setup.Do(func() { p.setupErr = p.setUp(client, stopCh) })
+	return p.setupErr
+}
+
+func (p *Process) setUp(client kubernetes.Interface, stopCh <-chan struct{}) error {
+	if err := p.preflight(p.baseContext(), client); err != nil {
+		return err
+	}
+	if err := p.tenants.load(p.settings.TenantPolicyFile); err != nil {
+		return err
+	}
+	if err := p.audit.open(p.settings.AuditLog); err != nil {
+		return err
+	}
+	var debugToken string
+	if p.settings.DebugAddress != "" {
+		var err error
+		if debugToken, err = readDebugToken(p.settings.DebugTokenFile); err != nil {
+			return err
+		}
+	}
+
+	p.setupTracing(stopCh)
+	if p.settings.DefaultsConfigMap != "" {
+		p.cluster.watch(client, p.settings.DefaultsNamespace, p.settings.DefaultsConfigMap, stopCh)
+	}
+	p.reloadOnSIGHUP(client, stopCh)
+	if p.settings.MetricsAddress != "" {
+		p.startMetricsServer(p.settings.MetricsAddress, stopCh)
+	}
+	if p.settings.HealthProbeAddress != "" {
+		p.health.start(p.settings.HealthProbeAddress, stopCh)
+	}
+	if p.settings.PprofAddress != "" {
+		addr, err := pprofListenAddress(p.settings.PprofAddress)
+		if err != nil {
+			return err
+		}
+		p.startPprofServer(addr, stopCh)
+	}
+	if p.settings.DebugAddress != "" {
+		p.startDebugServer(p.settings.DebugAddress, debugToken, stopCh)
+	}
+	return nil
+}
+
+// register adds c to the solvers the debug server and reloads cover.
+func (p *Process) register(c *Solver) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.solvers = append(p.solvers, c)
+}
+
+// initializedSolvers returns every solver Initialize has been called on.
+func (p *Process) initializedSolvers() []*Solver {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]*Solver(nil), p.solvers...)
+}
+
+// baseContext returns a background context carrying p's logger.
+func (p *Process) baseContext() context.Context {
+	return logr.NewContext(context.Background(), p.log)
+}
@@ -0,0 +1,118 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// propagationConfig makes Present wait until the authoritative nameservers
+// serve the TXT record, so cert-manager doesn't ask the ACME server to
+// validate before Nexus has published it.
+type propagationConfig struct {
+	Timeout  *metav1.Duration `json:"timeout,omitempty"`
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Mode is authoritative (the default) to find the zone's NS set and
+	// query each of those servers directly, or recursive to trust the
+	// resolvers instead, e.g. where outbound DNS is restricted to them, or
+	// doh to ask a DNS-over-HTTPS resolver, where outbound UDP/53 is
+	// blocked altogether.
+	Mode propagationMode `json:"mode,omitempty"`
+	// Nameservers are the resolvers, as host:port, used to discover the
+	// zone's nameservers, or queried in recursive mode. Defaults to the
+	// pod's resolv.conf. --prefer-ip-family puts its family first.
+	Nameservers []string `json:"nameservers,omitempty"`
+	// DoHURL is the resolver used in doh mode: cloudflare (the default),
+	// google, or an https URL.
+	DoHURL string `json:"dohURL,omitempty"`
+}
+
+type propagationMode string
+
+const (
+	propagationAuthoritative propagationMode = "authoritative"
+	propagationRecursive     propagationMode = "recursive"
+	propagationDoH           propagationMode = "doh"
+)
+
+const (
+	defaultPropagationTimeout  = time.Minute
+	defaultPropagationInterval = 5 * time.Second
+)
+
+func (p *propagationConfig) authoritative() bool {
+	return p.Mode != propagationRecursive
+}
+
+func (p *propagationConfig) nameservers() []string {
+	if len(p.Nameservers) > 0 {
+		return p.Nameservers
+	}
+	return util.RecursiveNameservers
+}
+
+// check reports whether DNS serves value at fqdn yet, preferring family to
+// reach nameservers.
+func (p *propagationConfig) check(ctx context.Context, family ipFamily, fqdn, value string) (bool, error) {
+	if p.Mode == propagationDoH {
+		endpoint, err := dohEndpoint(p.DoHURL)
+		if err != nil {
+			return false, err
+		}
+		return checkDoH(ctx, endpoint, fqdn, value)
+	}
+	return family.preCheckDNS(ctx, fqdn, value, p.nameservers(), p.authoritative())
+}
+
+func (p *propagationConfig) timeout() time.Duration {
+	if p.Timeout != nil {
+		return p.Timeout.Duration
+	}
+	return defaultPropagationTimeout
+}
+
+func (p *propagationConfig) interval() time.Duration {
+	if p.Interval != nil {
+		return p.Interval.Duration
+	}
+	return defaultPropagationInterval
+}
+
+// waitForPropagation polls DNS until the TXT record at fqdn holds value,
+// giving up with an error once the configured timeout passes. cert-manager
+// calls Present again after a failure, so a slow zone just takes another
+// round rather than failing the order.
+func waitForPropagation(ctx context.Context, family ipFamily, fqdn, value string, cfg *propagationConfig, log logr.Logger) (err error) {
+	_, span := tracer.Start(ctx, "WaitForPropagation")
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	deadline := time.After(cfg.timeout())
+	for {
+		live, checkErr := cfg.check(ctx, family, fqdn, value)
+		if checkErr != nil {
+			log.V(logf.DebugLevel).Info("propagation check failed", "error", checkErr.Error())
+		}
+		if live {
+			log.V(logf.InfoLevel).Info("record propagated", "duration", time.Since(start), "mode", cfg.Mode)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-deadline:
+			err = fmt.Errorf("record %s not visible in DNS after %s", fqdn, cfg.timeout())
+			return
+		case <-time.After(cfg.interval()):
+		}
+	}
+}
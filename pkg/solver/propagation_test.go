@@ -0,0 +1,64 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+func TestWaitForPropagation(t *testing.T) {
+	checks := 0
+	preCheckDNS := util.PreCheckDNS
+	defer func() { util.PreCheckDNS = preCheckDNS }()
+	util.PreCheckDNS = func(_ context.Context, fqdn, value string, nameservers []string, useAuthoritative bool) (bool, error) {
+		checks++
+		return checks >= 3, nil
+	}
+
+	fqdn := "_acme-challenge.example.com."
+	cfg := &propagationConfig{
+		Timeout:  &metav1.Duration{Duration: time.Second},
+		Interval: &metav1.Duration{Duration: time.Millisecond},
+	}
+	if err := waitForPropagation(context.Background(), "", fqdn, "token", cfg, klog.NewKlogr()); err != nil || checks != 3 {
+		t.Errorf("expected the record to propagate on the third check, got err=%v after %d checks", err, checks)
+	}
+
+	util.PreCheckDNS = func(context.Context, string, string, []string, bool) (bool, error) { return false, nil }
+	cfg.Timeout = &metav1.Duration{Duration: 20 * time.Millisecond}
+	if err := waitForPropagation(context.Background(), "", fqdn, "token", cfg, klog.NewKlogr()); err == nil {
+		t.Errorf("expected a timeout waiting for an unpropagated record")
+	}
+}
+
+func TestPropagationMode(t *testing.T) {
+	preCheckDNS := util.PreCheckDNS
+	defer func() { util.PreCheckDNS = preCheckDNS }()
+	var gotNameservers []string
+	var gotAuthoritative bool
+	util.PreCheckDNS = func(_ context.Context, _, _ string, nameservers []string, useAuthoritative bool) (bool, error) {
+		gotNameservers, gotAuthoritative = nameservers, useAuthoritative
+		return true, nil
+	}
+
+	fqdn := "_acme-challenge.example.com."
+	if err := waitForPropagation(context.Background(), "", fqdn, "token", &propagationConfig{}, klog.NewKlogr()); err != nil {
+		t.Fatal(err)
+	}
+	if !gotAuthoritative || len(gotNameservers) != len(util.RecursiveNameservers) {
+		t.Errorf("expected the authoritative nameservers to be found via resolv.conf by default, got %v, %v", gotAuthoritative, gotNameservers)
+	}
+
+	cfg := &propagationConfig{Mode: propagationRecursive, Nameservers: []string{"10.0.0.10:53"}}
+	if err := waitForPropagation(context.Background(), "", fqdn, "token", cfg, klog.NewKlogr()); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuthoritative || len(gotNameservers) != 1 || gotNameservers[0] != "10.0.0.10:53" {
+		t.Errorf("expected only the configured resolver to be queried, got %v, %v", gotAuthoritative, gotNameservers)
+	}
+}
@@ -0,0 +1,29 @@
+package solver
+
+import "context"
+
+// waitForNexusToken blocks until the client-side rate limit allows another
+// Nexus call, or ctx is done.
+func (p *Process) waitForNexusToken(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
+
+// acquireNexusSlot blocks until fewer than MaxConcurrentChallenges Nexus
+// calls are in flight, or ctx is done. The caller calls release once its
+// call has returned.
+func (p *Process) acquireNexusSlot(ctx context.Context) (release func(), err error) {
+	if p.slots == nil {
+		release = func() {}
+		return
+	}
+	select {
+	case p.slots <- struct{}{}:
+		release = func() { <-p.slots }
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
@@ -0,0 +1,115 @@
+package solver
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// startRecordVerifier periodically checks the records this replica
+// presented until cert-manager cleans them up, so a record deleted out of
+// band in Nexus doesn't leave the challenge failing until it times out.
+func (c *Solver) startRecordVerifier(interval time.Duration, stopCh <-chan struct{}) {
+	go wait.Until(func() { c.verifyRecords(c.baseContext(), interval) }, interval, stopCh)
+}
+
+// verifyRecords runs one verification pass over the challenges tracked in
+// memory that were presented at least minAge ago; younger records may not
+// have reached the nameservers yet.
+func (c *Solver) verifyRecords(ctx context.Context, minAge time.Duration) {
+	c.lock.Lock()
+	tracked := make(map[ChallengeKey]trackedChallenge, len(c.challenges))
+	for ck, tc := range c.challenges {
+		if tc.request != nil && time.Since(tc.presentedAt) >= minAge {
+			tracked[ck] = tc
+		}
+	}
+	c.lock.Unlock()
+
+	for ck, tc := range tracked {
+		c.verifyRecord(ctx, ck, tc)
+	}
+}
+
+// verifyRecord creates tc's record again if DNS no longer serves it. Nexus
+// can't list records, so DNS is the only way to tell one has gone.
+func (c *Solver) verifyRecord(ctx context.Context, ck ChallengeKey, tc trackedChallenge) {
+	ch := tc.request
+	ctx = withRequestID(ctx, ch)
+	log := challengeLogger(ctx, ch).WithValues("challengeId", tc.id)
+
+	cfg, err := c.challengeConfig(ctx, ch)
+	if err == nil {
+		err = c.validate(&cfg, ch.AllowAmbientCredentials)
+	}
+	if err != nil {
+		log.Error(err, "could not load config to verify record")
+		return
+	}
+	target, err := c.resolveTarget(ctx, ch, &cfg)
+	if err != nil {
+		log.Error(err, "could not resolve record to verify")
+		return
+	}
+	log = log.WithValues("record", target.record, "domain", target.domain)
+
+	served, err := c.recordServed(ctx, target.fqdn, ch.Key)
+	if err != nil {
+		log.V(logf.InfoLevel).Info("could not verify record", "error", err.Error())
+		return
+	}
+	if served {
+		return
+	}
+
+	nc, err := c.backendClient(ctx, ch, &cfg, target.domain, tc.backend)
+	if err != nil {
+		log.Error(err, "could not build client to recreate record")
+		return
+	}
+	defer c.challengeLocks.acquire(ck)()
+	c.lock.Lock()
+	current, ok := c.challenges[ck]
+	c.lock.Unlock()
+	if !ok || current.id != tc.id {
+		// Cleaned up or replaced while we were checking.
+		return
+	}
+
+	log.Info("presented record is no longer served, creating it again")
+	if err := c.deleteRecord(ctx, &cfg, nc, ch, target, tc.id, log); err != nil {
+		log.V(logf.DebugLevel).Info("could not delete missing record", "error", err.Error())
+	}
+	id, err := c.createRecord(ctx, &cfg, nc, ch, target, log)
+	if err != nil {
+		nexusErrorsTotal.WithLabelValues(opPresent).Inc()
+		log.Error(err, "failed to recreate challenge record")
+		return
+	}
+	log.Info("recreated record", "newChallengeId", id)
+	recordsRecreatedTotal.Inc()
+
+	tc.id = id
+	c.lock.Lock()
+	c.challenges[ck] = tc
+	c.lock.Unlock()
+	if c.store != nil {
+		storeCtx, cancel := c.proc.withKubeTimeout(ctx)
+		defer cancel()
+		sc := StoredChallenge{ID: tc.id, PresentedAt: tc.presentedAt, Request: ch, Solver: c.Name(), Zone: tc.zone, Backend: tc.backend}
+		if err := c.store.Put(storeCtx, ck, sc); err != nil {
+			log.Error(err, "could not store recreated record")
+		}
+	}
+}
+
+func (c *Solver) recordServed(ctx context.Context, fqdn, value string) (bool, error) {
+	if c.checkRecord != nil {
+		return c.checkRecord(ctx, fqdn, value)
+	}
+	family := c.proc.family()
+	return family.preCheckDNS(ctx, fqdn, value, family.recursiveNameservers(), true)
+}
@@ -0,0 +1,53 @@
+package solver
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerifyRecords(t *testing.T) {
+	c, server, ch := newTestSolver(t, "")
+	served := true
+	c.checkRecord = func(ctx context.Context, fqdn, value string) (bool, error) {
+		return served, nil
+	}
+
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	ck := newChallengeKey(ch)
+	original := c.challenges[ck].id
+
+	c.verifyRecords(context.Background(), 0)
+	if id := c.challenges[ck].id; id != original {
+		t.Fatalf("expected a served record to be left alone, got %s instead of %s", id, original)
+	}
+
+	// Delete the record behind the webhook's back.
+	nc, _ := server.Client("example.com", "svc", []byte("secret"))
+	if err := nc.DeleteChallengeRecord(original); err != nil {
+		t.Fatalf("DeleteChallengeRecord: %v", err)
+	}
+	served = false
+	c.verifyRecords(context.Background(), 0)
+
+	recreated := c.challenges[ck].id
+	if recreated == original {
+		t.Fatal("expected the missing record to be recreated")
+	}
+	records := server.Records()
+	if r, ok := records[recreated]; !ok || r.Value != ch.Key || len(records) != 1 {
+		t.Errorf("expected only the recreated record in Nexus, got %v", records)
+	}
+	sc, ok, err := c.store.Get(context.Background(), ck)
+	if err != nil || !ok || sc.ID != recreated {
+		t.Errorf("expected the store to hold the recreated record, got %+v, %v, %v", sc, ok, err)
+	}
+
+	if err := c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Errorf("expected CleanUp to delete the recreated record, got %v", records)
+	}
+}
@@ -0,0 +1,82 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reloader is implemented by credential providers that cache what they
+// fetch, such as Vault login tokens.
+type reloader interface {
+	reload()
+}
+
+// reloadOnSIGHUP makes SIGHUP re-read the cluster defaults and tenant
+// policy and drop cached credentials, for operators who change them and
+// don't want to wait for the watch or the cache TTL, or restart the webhook.
+func (p *Process) reloadOnSIGHUP(client kubernetes.Interface, stopCh <-chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-signals:
+				ctx, cancel := p.withKubeTimeout(p.baseContext())
+				if err := p.reload(ctx, client); err != nil {
+					p.log.Error(err, "reload failed")
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// reload re-reads the cluster defaults and tenant policy, and makes every
+// solver read its credentials and zones afresh on the next challenge.
+func (p *Process) reload(ctx context.Context, client kubernetes.Interface) (err error) {
+	p.log.Info("reloading defaults and credentials")
+	p.zones.flush()
+	for _, c := range p.initializedSolvers() {
+		c.clients.flush()
+		for _, provider := range c.credentials {
+			if r, ok := provider.(reloader); ok {
+				r.reload()
+			}
+		}
+	}
+	if policyErr := p.tenants.load(p.settings.TenantPolicyFile); policyErr != nil {
+		err = policyErr
+	}
+	if path := p.settings.APIKeyFile; path != "" {
+		if _, statErr := os.Stat(path); statErr != nil {
+			err = errors.Join(err, fmt.Errorf("api key file: %w", statErr))
+		}
+	}
+
+	if getErr := p.loadClusterDefaults(ctx, client); getErr != nil {
+		err = errors.Join(err, fmt.Errorf("cluster defaults: %w", getErr))
+	}
+	return
+}
+
+// loadClusterDefaults reads the DefaultsConfigMap once, if it's set.
+func (p *Process) loadClusterDefaults(ctx context.Context, client kubernetes.Interface) error {
+	if p.settings.DefaultsConfigMap == "" {
+		return nil
+	}
+	cm, err := client.CoreV1().ConfigMaps(p.settings.DefaultsNamespace).Get(ctx, p.settings.DefaultsConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return p.cluster.load(cm.Data[defaultsKey])
+}
@@ -0,0 +1,41 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+func TestReload(t *testing.T) {
+	s := DefaultSettings()
+	s.DefaultsConfigMap, s.DefaultsNamespace = "nexus-defaults", "cert-manager"
+	c := New(WithProcess(newProcess(s)))
+	c.clients.ttl = time.Hour
+	if _, err := c.clients.get(nexusclient.V1, "example.com", "svc", []byte("key"), func() (nexusclient.API, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	vault := c.credentials[providerVault].(*vaultClient)
+	vault.tokens = map[vaultLogin]vaultToken{{address: "https://vault"}: {token: "t", expires: time.Now().Add(time.Hour)}}
+	c.proc.register(c)
+
+	kube := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus-defaults", Namespace: "cert-manager"},
+		Data:       map[string]string{defaultsKey: `{"service": "reloaded"}`},
+	})
+	if err := c.proc.reload(context.Background(), kube); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.clients.entries) != 0 || len(vault.tokens) != 0 {
+		t.Errorf("expected cached clients and tokens to be dropped, got %v, %v", c.clients.entries, vault.tokens)
+	}
+	if cfg, err := c.config(nil); err != nil || cfg.Service != "reloaded" {
+		t.Errorf("expected the cluster defaults to be re-read, got %+v, %v", cfg, err)
+	}
+}
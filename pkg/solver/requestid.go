@@ -0,0 +1,51 @@
+package solver
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+type requestIDKey struct{}
+
+// withRequestID returns ctx carrying the correlation ID for ch: the UID
+// cert-manager gave the request, or a fresh one if it has none. Every log
+// line and Nexus call span made on behalf of ch carries it, so the steps
+// of one Present or CleanUp can be picked out of interleaved logs.
+func withRequestID(ctx context.Context, ch *v1alpha1.ChallengeRequest) context.Context {
+	id := string(ch.UID)
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextLogger returns the solver's logger carried by ctx, annotated with
+// ctx's request ID if it has one. Without one it logs through klog, as the
+// default text format does.
+func contextLogger(ctx context.Context) logr.Logger {
+	log, err := logr.FromContext(ctx)
+	if err != nil {
+		log = klog.NewKlogr().WithName("nexus")
+	}
+	if id := requestIDFrom(ctx); id != "" {
+		return log.WithValues("requestId", id)
+	}
+	return log
+}
+
+// requestAttributes returns span attributes carrying ctx's request ID.
+func requestAttributes(ctx context.Context) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("request.id", requestIDFrom(ctx)))
+}
@@ -0,0 +1,32 @@
+package solver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestRequestID(t *testing.T) {
+	ctx := withRequestID(context.Background(), &v1alpha1.ChallengeRequest{UID: "req-1"})
+	if id := requestIDFrom(ctx); id != "req-1" {
+		t.Errorf("expected the request UID to be used, got %q", id)
+	}
+
+	first := requestIDFrom(withRequestID(context.Background(), &v1alpha1.ChallengeRequest{}))
+	second := requestIDFrom(withRequestID(context.Background(), &v1alpha1.ChallengeRequest{}))
+	if first == "" || first == second {
+		t.Errorf("expected a fresh ID per request without a UID, got %q and %q", first, second)
+	}
+
+	var lines []string
+	log := funcr.New(func(prefix, args string) { lines = append(lines, args) }, funcr.Options{})
+	challengeLogger(logr.NewContext(ctx, log), &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com."}).Info("presenting record")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"requestId"="req-1"`) {
+		t.Errorf("expected the log line to carry the request ID, got %q", lines)
+	}
+}
@@ -0,0 +1,156 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+)
+
+// retryConfig controls how failed Nexus API calls are retried. Zero values
+// fall back to the defaults below.
+type retryConfig struct {
+	MaxAttempts          int              `json:"maxAttempts,omitempty"`
+	InitialBackoff       *metav1.Duration `json:"initialBackoff,omitempty"`
+	MaxBackoff           *metav1.Duration `json:"maxBackoff,omitempty"`
+	RetryableStatusCodes []int            `json:"retryableStatusCodes,omitempty"`
+	// ChallengeAttempts and ChallengeTimeout bound the Present calls for one
+	// challenge: after that many failures, or that long since the first,
+	// Present fails without calling Nexus until the challenge is cleaned
+	// up. Zero means no limit.
+	ChallengeAttempts int              `json:"challengeAttempts,omitempty"`
+	ChallengeTimeout  *metav1.Duration `json:"challengeTimeout,omitempty"`
+}
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 10 * time.Second
+)
+
+var defaultRetryableStatusCodes = []int{429, 500, 502, 503, 504}
+
+// statusPattern matches an HTTP status as formatted by net/http, e.g.
+// "503 Service Unavailable", for errors that only carry it in their text.
+var statusPattern = regexp.MustCompile(`\b([1-5][0-9]{2}) [A-Z]`)
+
+func (r retryConfig) backoff() wait.Backoff {
+	b := wait.Backoff{
+		Duration: defaultInitialBackoff,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    r.attempts(),
+		Cap:      defaultMaxBackoff,
+	}
+	if r.InitialBackoff != nil {
+		b.Duration = r.InitialBackoff.Duration
+	}
+	if r.MaxBackoff != nil {
+		b.Cap = r.MaxBackoff.Duration
+	}
+	return b
+}
+
+func (r retryConfig) attempts() int {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+// retryable reports whether err looks transient: a network error or timeout,
+// or an HTTP status listed in RetryableStatusCodes.
+func (r retryConfig) retryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code, ok := statusCode(err)
+	if !ok {
+		return false
+	}
+	codes := r.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryableStatusCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableCreate is retryable for calls that aren't idempotent, like
+// creating a record: a retry after Nexus may have acted on the first
+// attempt leaves a duplicate record whose ID is lost. So only failures
+// where the request can't have been acted on are retried: a connection
+// that was never made, or a configured 429 or 503 turning it away. Timeouts,
+// other network errors, 500s and gateway errors are not.
+func (r retryConfig) retryableCreate(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	code, ok := statusCode(err)
+	if !ok || code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		return false
+	}
+	return r.retryable(err)
+}
+
+func statusCode(err error) (int, bool) {
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) {
+		return coded.StatusCode(), true
+	}
+	m := statusPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code, true
+}
+
+// withRetry calls op until it succeeds, fails with an error retryable
+// rejects, runs out of attempts, or ctx is done. A retryable error that
+// outlasts the retries is wrapped in ErrNexusUnavailable.
+func withRetry(ctx context.Context, cfg retryConfig, retryable func(error) bool, log logr.Logger, op func() error) (err error) {
+	backoff := cfg.backoff()
+	attempts := cfg.attempts()
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !retryable(err) {
+			return
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("%w: %w", ErrNexusUnavailable, err)
+		}
+
+		delay := backoff.Step()
+		log.V(logf.InfoLevel).Info("retrying nexus call", "attempt", attempt, "delay", delay, "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrNexusUnavailable, err)
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/nexustest"
+)
+
+func TestRetryable(t *testing.T) {
+	cfg := retryConfig{}
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{errors.New("failed to create record: 503 Service Unavailable"), true},
+		{errors.New("failed to create record: 429 Too Many Requests"), true},
+		{errors.New("failed to create record: 401 Unauthorized"), false},
+		{errors.New("invalid key"), false},
+		{fmt.Errorf("dial: %w", timeoutError{}), true},
+	}
+	for _, test := range tests {
+		if got := cfg.retryable(test.err); got != test.retryable {
+			t.Errorf("retryable(%q) = %v, expected %v", test.err, got, test.retryable)
+		}
+	}
+
+	custom := retryConfig{RetryableStatusCodes: []int{401}}
+	if !custom.retryable(errors.New("401 Unauthorized")) {
+		t.Errorf("expected configured status code to be retryable")
+	}
+	if custom.retryable(errors.New("503 Service Unavailable")) {
+		t.Errorf("expected configured codes to replace the defaults")
+	}
+}
+
+func TestRetryableCreate(t *testing.T) {
+	cfg := retryConfig{}
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("post: %w", &net.DNSError{Err: "no such host", Name: "nexus.example"}), true},
+		{errors.New("failed to create record: 503 Service Unavailable"), true},
+		{errors.New("failed to create record: 429 Too Many Requests"), true},
+		{errors.New("failed to create record: 500 Internal Server Error"), false},
+		{errors.New("failed to create record: 504 Gateway Timeout"), false},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, false},
+		{fmt.Errorf("nexus call abandoned: %w", context.DeadlineExceeded), false},
+	}
+	for _, test := range tests {
+		if got := cfg.retryableCreate(test.err); got != test.retryable {
+			t.Errorf("retryableCreate(%q) = %v, expected %v", test.err, got, test.retryable)
+		}
+	}
+
+	custom := retryConfig{RetryableStatusCodes: []int{429}}
+	if custom.retryableCreate(errors.New("503 Service Unavailable")) {
+		t.Errorf("expected only configured status codes to be retried")
+	}
+}
+
+func TestPresentDoesNotRetrySlowCreate(t *testing.T) {
+	c, server, ch := newTestSolver(t, `{"retry": {"maxAttempts": 3, "initialBackoff": "1ms"}}`)
+	c.proc.settings.NexusCallTimeout = 20 * time.Millisecond
+	server.Delay = 100 * time.Millisecond
+	if err := c.Present(ch); err == nil {
+		t.Fatal("expected the slow create to time out")
+	}
+
+	// The record the abandoned create made is deleted once it returns.
+	var creates, deletes int
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		creates, deletes = 0, 0
+		for _, req := range server.Requests() {
+			switch req.Op {
+			case nexustest.OpCreate:
+				creates++
+			case nexustest.OpDelete:
+				deletes++
+			}
+		}
+		if deletes > 0 && len(server.Records()) == 0 {
+			break
+		}
+	}
+	// Let the delete, slowed like the create, return before other tests
+	// reset the limits it goes through.
+	time.Sleep(2 * server.Delay)
+	if creates != 1 {
+		t.Errorf("expected the timed-out create not to be retried, got %d creates", creates)
+	}
+	if records := server.Records(); deletes != 1 || len(records) != 0 {
+		t.Errorf("expected the late record to be deleted once, got %d deletes and records %v", deletes, records)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	cfg := retryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: &metav1.Duration{Duration: time.Millisecond},
+	}
+
+	calls := 0
+	err := withRetry(context.Background(), cfg, cfg.retryable, klog.NewKlogr(), func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("502 Bad Gateway")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("expected success on second attempt, got err=%v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = withRetry(context.Background(), cfg, cfg.retryable, klog.NewKlogr(), func() error {
+		calls++
+		return errors.New("502 Bad Gateway")
+	})
+	if !errors.Is(err, ErrNexusUnavailable) || calls != 3 {
+		t.Errorf("expected ErrNexusUnavailable after 3 attempts, got err=%v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = withRetry(context.Background(), cfg, cfg.retryable, klog.NewKlogr(), func() error {
+		calls++
+		return errors.New("403 Forbidden")
+	})
+	if err == nil || errors.Is(err, ErrNexusUnavailable) || calls != 1 {
+		t.Errorf("expected no retries for a permanent error, got err=%v after %d calls", err, calls)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
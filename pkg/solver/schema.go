@@ -0,0 +1,139 @@
+package solver
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+// ActionGenSchema is the subcommand that prints the solver config's JSON
+// Schema.
+const ActionGenSchema = "gen-schema"
+
+// durationPattern matches what time.ParseDuration accepts, bar signs.
+const durationPattern = `^(0|([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+$`
+
+var (
+	durationType = reflect.TypeOf(metav1.Duration{})
+	rawJSONType  = reflect.TypeOf(extapi.JSON{})
+)
+
+// schemaEnums lists the values validate accepts for string fields, keyed by
+// type and field name, so the schema can't drift from the constants.
+func schemaEnums() map[string][]string {
+	versions := make([]string, 0, len(nexusclient.Versions))
+	for v := range nexusclient.Versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return map[string][]string{
+		"Config.Encoding":   {encodingBase64, encodingPlain},
+		"Config.APIVersion": versions,
+		"propagationConfig.Mode": {
+			string(propagationAuthoritative), string(propagationRecursive), string(propagationDoH),
+		},
+	}
+}
+
+// GenSchema prints the JSON Schema of the solver config, the webhook.config
+// block of an Issuer, so configs can be checked in CI before they're
+// applied.
+func GenSchema(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(ActionGenSchema, flag.ExitOnError)
+	fs.Parse(args)
+
+	out, err := json.MarshalIndent(ConfigSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// ConfigSchema returns the JSON Schema of the solver config, built from the
+// json tags of Config. Like loadConfig, it rejects unknown fields, though
+// field names must match case exactly.
+func ConfigSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(Config{}), schemaEnums())
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "Config"
+	return s
+}
+
+func typeSchema(t reflect.Type, enums map[string][]string) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	case t == rawJSONType:
+		// Passed through unchanged, e.g. to a delegate solver.
+		return map[string]interface{}{}
+	case t.Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(jsonUnmarshaler):
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		addProperties(properties, t, enums)
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), enums)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), enums)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// addProperties adds the schemas of t's fields to properties by JSON name,
+// including fields promoted from embedded structs, as jsonFields does.
+func addProperties(properties map[string]interface{}, t reflect.Type, enums map[string][]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addProperties(properties, ft, enums)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := typeSchema(f.Type, enums)
+		if values, ok := enums[t.Name()+"."+f.Name]; ok {
+			// Empty means the default.
+			s["enum"] = append([]string{""}, values...)
+		}
+		properties[name] = s
+	}
+}
@@ -0,0 +1,70 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GenSchema(nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("gen-schema printed invalid JSON: %v", err)
+	}
+
+	// Every field loadConfig accepts is in the schema, and nothing else.
+	properties := schema["properties"].(map[string]interface{})
+	for name := range jsonFields(reflect.TypeOf(Config{})) {
+		found := false
+		for property := range properties {
+			if strings.ToLower(property) == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("schema has no property for config field %s", name)
+		}
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("expected unknown fields to be rejected")
+	}
+	if schema["title"] != reflect.TypeOf(Config{}).Name() {
+		t.Errorf("expected the schema to be titled after the config type, got %v", schema["title"])
+	}
+
+	ref := properties["apikeysecret"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, name := range []string{"name", "key", "namespace", "selector"} {
+		if _, ok := ref[name]; !ok {
+			t.Errorf("expected apikeysecret.%s in the schema, got %v", name, ref)
+		}
+	}
+
+	mode := properties["propagationCheck"].(map[string]interface{})["properties"].(map[string]interface{})["mode"].(map[string]interface{})
+	if got, want := mode["enum"], []interface{}{"", "authoritative", "recursive", "doh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("propagationCheck.mode enum = %v, expected %v", got, want)
+	}
+
+	timeout := properties["presentTimeout"].(map[string]interface{})
+	if timeout["type"] != "string" {
+		t.Errorf("expected durations to be strings, got %v", timeout)
+	}
+	pattern := regexp.MustCompile(timeout["pattern"].(string))
+	for _, d := range []string{"0", "90s", "1h30m", "1.5s", "250ms"} {
+		if _, err := time.ParseDuration(d); err != nil || !pattern.MatchString(d) {
+			t.Errorf("expected %q to match the duration pattern", d)
+		}
+	}
+	for _, d := range []string{"", "90", "5 minutes"} {
+		if pattern.MatchString(d) {
+			t.Errorf("expected %q not to match the duration pattern", d)
+		}
+	}
+}
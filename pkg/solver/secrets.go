@@ -0,0 +1,113 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// secretLister serves Secret reads from informer caches. An informer is
+// started for each namespace the first time a challenge references it, so
+// the webhook only watches namespaces it actually needs.
+type secretLister struct {
+	client kubernetes.Interface
+	stopCh <-chan struct{}
+
+	// onRotate, if set, is called with the previous contents of a Secret
+	// whose data changed or which was deleted.
+	onRotate func(old *corev1.Secret)
+
+	lock       sync.Mutex
+	namespaces map[string]*namespacedSecrets
+}
+
+type namespacedSecrets struct {
+	lister corelisters.SecretNamespaceLister
+	synced cache.InformerSynced
+}
+
+func newSecretLister(client kubernetes.Interface, stopCh <-chan struct{}) *secretLister {
+	return &secretLister{
+		client:     client,
+		stopCh:     stopCh,
+		namespaces: map[string]*namespacedSecrets{},
+	}
+}
+
+func (l *secretLister) get(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	secrets := l.forNamespace(namespace)
+	if !cache.WaitForCacheSync(ctx.Done(), secrets.synced) {
+		return nil, fmt.Errorf("timed out waiting for secret cache of namespace %s to sync", namespace)
+	}
+	return secrets.lister.Get(name)
+}
+
+func (l *secretLister) list(ctx context.Context, namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	secrets := l.forNamespace(namespace)
+	if !cache.WaitForCacheSync(ctx.Done(), secrets.synced) {
+		return nil, fmt.Errorf("timed out waiting for secret cache of namespace %s to sync", namespace)
+	}
+	return secrets.lister.List(selector)
+}
+
+func (l *secretLister) forNamespace(namespace string) *namespacedSecrets {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if secrets, ok := l.namespaces[namespace]; ok {
+		return secrets
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(l.client, 0, informers.WithNamespace(namespace))
+	informer := factory.Core().V1().Secrets()
+	secrets := &namespacedSecrets{
+		lister: informer.Lister().Secrets(namespace),
+		synced: informer.Informer().HasSynced,
+	}
+	if l.onRotate != nil {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				old, ok := oldObj.(*corev1.Secret)
+				if !ok {
+					return
+				}
+				if updated, ok := newObj.(*corev1.Secret); ok && sameData(old, updated) {
+					return
+				}
+				l.onRotate(old)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if old, ok := obj.(*corev1.Secret); ok {
+					l.onRotate(old)
+				}
+			},
+		})
+	}
+	factory.Start(l.stopCh)
+
+	l.namespaces[namespace] = secrets
+	return secrets
+}
+
+func sameData(a, b *corev1.Secret) bool {
+	if len(a.Data) != len(b.Data) {
+		return false
+	}
+	for k, v := range a.Data {
+		if w, ok := b.Data[k]; !ok || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretLister(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus-key", Namespace: "team-a"},
+		Data:       map[string][]byte{"key": []byte("c2VjcmV0")},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	lister := newSecretLister(client, stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	secret, err := lister.get(ctx, "team-a", "nexus-key")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(secret.Data["key"]) != "c2VjcmV0" {
+		t.Errorf("unexpected secret data %q", secret.Data["key"])
+	}
+
+	if _, err := lister.get(ctx, "team-b", "nexus-key"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found in another namespace, got %v", err)
+	}
+}
+
+func TestSecretListerRotation(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus-key", Namespace: "team-a"},
+		Data:       map[string][]byte{"key": []byte("old")},
+	}
+	client := fake.NewSimpleClientset(secret)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	rotated := make(chan string, 4)
+	lister := newSecretLister(client, stopCh)
+	lister.onRotate = func(old *corev1.Secret) { rotated <- string(old.Data["key"]) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := lister.get(ctx, "team-a", "nexus-key"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	relabeled := secret.DeepCopy()
+	relabeled.Labels = map[string]string{"touched": "true"}
+	if _, err := client.CoreV1().Secrets("team-a").Update(ctx, relabeled, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	updated := relabeled.DeepCopy()
+	updated.Data["key"] = []byte("new")
+	if _, err := client.CoreV1().Secrets("team-a").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	select {
+	case old := <-rotated:
+		if old != "old" {
+			t.Errorf("expected rotation to report the previous key, got %q", old)
+		}
+	case <-ctx.Done():
+		t.Fatal("rotation was not reported")
+	}
+
+	if err := client.CoreV1().Secrets("team-a").Delete(ctx, "nexus-key", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	select {
+	case old := <-rotated:
+		if old != "new" {
+			t.Errorf("expected deletion to report the last key, got %q", old)
+		}
+	case <-ctx.Done():
+		t.Fatal("deletion was not reported")
+	}
+}
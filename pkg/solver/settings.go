@@ -0,0 +1,194 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Settings are the webhook-wide settings that aren't part of an Issuer's
+// config. cmd/webhook fills them in from its command-line flags, which
+// are named after the fields. Start from DefaultSettings: the zero value
+// turns off things the webhook does by default, such as zone write
+// serialization and the secret informer.
+type Settings struct {
+	// LogFormat is "text" or "json".
+	LogFormat string
+	// AuditLog is a file to append a JSON record to for every DNS record
+	// created or deleted. They go to the log if empty.
+	AuditLog string
+	// EmitEvents records failures as Events on the affected Challenge.
+	EmitEvents bool
+
+	// APIKeyFile holds the Nexus API key the file provider reads.
+	APIKeyFile string
+	// CredentialCommand is run by the external provider, and prints the
+	// Nexus API key.
+	CredentialCommand string
+	// AllowedSecretNamespaces may hold apikeysecret Secrets, besides the
+	// issuer's own namespace.
+	AllowedSecretNamespaces []string
+	// SecretInformer serves Secrets from an informer cache instead of
+	// reading them on every challenge.
+	SecretInformer bool
+	// RBACPreflightNamespaces are checked at startup, with
+	// AllowedSecretNamespaces, for permission to read Secrets.
+	RBACPreflightNamespaces []string
+	// RBACPreflightStrict fails startup on missing permissions instead of
+	// only logging them.
+	RBACPreflightStrict bool
+
+	// StateConfigMap persists record IDs across restarts, in
+	// StateNamespace. Disabled if empty.
+	StateConfigMap string
+	// StateCRD persists record IDs as NexusChallenge resources instead.
+	StateCRD       bool
+	StateNamespace string
+	// OrphanGCInterval is how often to clean up stored records presented
+	// at least OrphanGCMinAge ago whose Challenge is gone. Needs state.
+	OrphanGCInterval time.Duration
+	OrphanGCMinAge   time.Duration
+	// RecordVerifyInterval is how often to check presented records are
+	// still served, creating them again if not. Disabled if zero.
+	RecordVerifyInterval time.Duration
+
+	// DefaultsConfigMap, in DefaultsNamespace, holds config fields every
+	// solver applies under its own defaults. Watched for changes.
+	DefaultsConfigMap string
+	DefaultsNamespace string
+	// IssuerDefaults applies the NexusIssuerDefaults named after each
+	// challenge's issuer.
+	IssuerDefaults bool
+	// ConfigEnvPrefix is the prefix of the environment variables solver
+	// config may refer to as ${NAME}. Empty disables expansion.
+	ConfigEnvPrefix string
+
+	// AllowedZones, if set, are the only zones records may be created in.
+	AllowedZones []string
+	// DeniedZones are never written, even within an allowed zone.
+	DeniedZones []string
+	// TenantPolicyFile holds rules limiting the zones and services each
+	// namespace may use. Unrestricted if empty.
+	TenantPolicyFile string
+
+	// NexusQPS and NexusBurst limit the rate of Nexus calls across all
+	// challenges. Zero QPS means no limit.
+	NexusQPS   float64
+	NexusBurst int
+	// MaxConcurrentChallenges limits the Nexus calls in flight at once.
+	// Zero means no limit.
+	MaxConcurrentChallenges int
+	// SerializeZoneWrites sends one create or delete at a time to each
+	// zone.
+	SerializeZoneWrites bool
+	// ClientCacheTTL is how long to reuse a Nexus client. Zero disables
+	// caching.
+	ClientCacheTTL time.Duration
+	// FailureStreakWarning logs a warning each time this many writes in a
+	// row fail for one zone. Zero disables it.
+	FailureStreakWarning int
+
+	NexusCallTimeout time.Duration
+	KubeCallTimeout  time.Duration
+	// PresentTimeout and CleanupTimeout bound whole calls, retries
+	// included, unless the issuer sets its own. Zero means no limit.
+	PresentTimeout time.Duration
+	CleanupTimeout time.Duration
+	// DrainTimeout is how long Drain waits for calls in flight.
+	DrainTimeout time.Duration
+
+	// PreferIPFamily is "IPv4" or "IPv6" to reach nameservers over that
+	// family where they have one. Empty leaves it to the resolver.
+	PreferIPFamily string
+	// ZoneCacheTTL is how long to remember a name's zone, and
+	// ZoneCacheNegativeTTL that it has none, for up to ZoneCacheSize
+	// names. Zero TTL disables the cache.
+	ZoneCacheTTL         time.Duration
+	ZoneCacheNegativeTTL time.Duration
+	ZoneCacheSize        int
+
+	// The addresses below serve nothing if empty.
+	MetricsAddress string
+	// MetricsCertFile and MetricsKeyFile serve metrics over HTTPS.
+	MetricsCertFile string
+	MetricsKeyFile  string
+	// HealthProbeAddress serves /healthz and /readyz.
+	HealthProbeAddress string
+	// ReadinessNexusURL, if set, must answer without a server error for
+	// /readyz to pass.
+	ReadinessNexusURL string
+	// StartupCredentialCheck stays not ready until a solver's default API
+	// key can be read.
+	StartupCredentialCheck bool
+	// PprofAddress must be a loopback address.
+	PprofAddress string
+	// DebugAddress serves /debug to requests bearing the token in
+	// DebugTokenFile.
+	DebugAddress   string
+	DebugTokenFile string
+	// OTLPTracesEndpoint is the OTLP/HTTP URL traces are exported to.
+	OTLPTracesEndpoint string
+}
+
+// DefaultSettings returns the settings the webhook runs with when no flags
+// are given, apart from those cmd/webhook reads from the environment.
+func DefaultSettings() Settings {
+	return Settings{
+		LogFormat:            "text",
+		EmitEvents:           true,
+		SecretInformer:       true,
+		OrphanGCMinAge:       time.Hour,
+		ConfigEnvPrefix:      "NEXUS_CONFIG_",
+		NexusQPS:             5,
+		NexusBurst:           10,
+		SerializeZoneWrites:  true,
+		ClientCacheTTL:       10 * time.Minute,
+		FailureStreakWarning: 5,
+		NexusCallTimeout:     30 * time.Second,
+		KubeCallTimeout:      10 * time.Second,
+		DrainTimeout:         25 * time.Second,
+		ZoneCacheTTL:         time.Hour,
+		ZoneCacheNegativeTTL: time.Minute,
+		ZoneCacheSize:        1024,
+		MetricsAddress:       ":9402",
+		HealthProbeAddress:   ":6080",
+	}
+}
+
+// validate reports settings that can't work together, before anything is
+// started.
+func (s *Settings) validate() error {
+	switch s.LogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown log format %q, expected 'text' or 'json'", s.LogFormat)
+	}
+	switch s.PreferIPFamily {
+	case "", ipFamilyIPv4, ipFamilyIPv6:
+	default:
+		return fmt.Errorf("--prefer-ip-family must be %q or %q, got %q", ipFamilyIPv4, ipFamilyIPv6, s.PreferIPFamily)
+	}
+	if s.PprofAddress != "" {
+		if _, err := pprofListenAddress(s.PprofAddress); err != nil {
+			return err
+		}
+	}
+	if err := s.checkMetricsTLS(); err != nil {
+		return err
+	}
+	if s.DebugAddress != "" && s.DebugTokenFile == "" {
+		return errors.New("--debug-bind-address requires --debug-token-file")
+	}
+	if s.DefaultsConfigMap != "" && s.DefaultsNamespace == "" {
+		return errors.New("--defaults-namespace (or $POD_NAMESPACE) is required with --defaults-configmap")
+	}
+	if s.StateConfigMap != "" || s.StateCRD {
+		if s.StateNamespace == "" {
+			return errors.New("--state-namespace (or $POD_NAMESPACE) is required with --state-configmap or --state-crd")
+		}
+		if s.StateConfigMap != "" && s.StateCRD {
+			return errors.New("--state-configmap and --state-crd can't both be set")
+		}
+	}
+	return nil
+}
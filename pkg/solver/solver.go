@@ -0,0 +1,1082 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+)
+
+// Solver is a cert-manager DNS01 webhook solver that publishes challenge
+// records through Nexus. Create one with New.
+type Solver struct {
+	client kubernetes.Interface
+
+	// proc holds the settings and state shared with the other solvers in
+	// the process.
+	proc *Process
+
+	// challenges maps each presented (FQDN, key) pair to the record Nexus
+	// created for it, so overlapping orders don't clobber each other.
+	lock       sync.Mutex
+	challenges map[ChallengeKey]trackedChallenge
+
+	// store, if set, durably records challenges so CleanUp still works
+	// after the pod restarts.
+	store StateStore
+
+	// events, if set, reports failures on the affected Challenge.
+	events *eventRecorder
+
+	clients clientCache
+
+	// secrets, if set, serves API key Secrets from an informer cache.
+	secrets *secretLister
+
+	// credentials holds an instance of every registered provider.
+	credentials map[string]CredentialProvider
+
+	// newClient builds Nexus clients; nil means the real Nexus API.
+	newClient nexusclient.NewFunc
+
+	// checkRecord reports whether DNS serves value at fqdn; nil means the
+	// recursive nameservers.
+	checkRecord func(ctx context.Context, fqdn, value string) (bool, error)
+
+	inflight inflightTracker
+
+	challengeLocks challengeLocks
+	presents       presentCalls
+	zoneLocks      zoneLocks
+	cooldowns      nexusCooldowns
+	budgets        retryBudgets
+	// active maps the challenges a Present or CleanUp is working on to
+	// its state, for /debug/challenges. Guarded by lock.
+	active map[ChallengeKey]string
+
+	// log, if set, replaces the process's logger for this solver.
+	log logr.Logger
+
+	// name is the solver name Issuers refer to; empty means defaultName.
+	name string
+	// groupName is the API group the solver is served under, if known.
+	groupName string
+	// issuerDefaults, if set, looks up config fields for each challenge's
+	// issuer.
+	issuerDefaults *issuerDefaults
+
+	// defaults, if set, is a JSON object of config fields applied to
+	// every challenge unless its Issuer sets them.
+	defaults []byte
+}
+
+const defaultName = "nexus"
+
+// Option configures a Solver built by New.
+type Option func(*Solver)
+
+// WithLogger makes the solver log to l instead of the logger chosen by its
+// Process's LogFormat.
+func WithLogger(l logr.Logger) Option {
+	return func(c *Solver) { c.log = l }
+}
+
+// WithClient sets the clientset used to read Secrets and store state,
+// instead of building one from the config Initialize is given.
+func WithClient(client kubernetes.Interface) Option {
+	return func(c *Solver) { c.client = client }
+}
+
+// WithCredentialProvider adds p as the credential provider called name,
+// replacing any built-in provider of that name. Issuers select it with
+// credentialSource.provider.
+func WithCredentialProvider(name string, p CredentialProvider) Option {
+	return func(c *Solver) { c.credentials[name] = p }
+}
+
+// WithName serves the solver under name instead of "nexus", so one binary
+// can run several differently configured solvers.
+func WithName(name string) Option {
+	return func(c *Solver) { c.name = name }
+}
+
+// WithGroupName tells the solver the API group it's served under, so
+// validation can reject a delegate that points back at it.
+func WithGroupName(group string) Option {
+	return func(c *Solver) { c.groupName = group }
+}
+
+// WithStateStore makes the solver keep challenge record IDs in store,
+// instead of the store its Process's settings pick.
+func WithStateStore(store StateStore) Option {
+	return func(c *Solver) { c.store = store }
+}
+
+// WithDefaultConfig sets config fields, as a JSON object, that apply to
+// every challenge unless its Issuer sets them. Fields are replaced whole:
+// an Issuer that sets retry replaces all of the default retry settings.
+func WithDefaultConfig(defaults []byte) Option {
+	return func(c *Solver) { c.defaults = defaults }
+}
+
+// New returns a Solver with the built-in credential providers, configured
+// by opts. Without WithProcess it gets a Process of its own, with
+// DefaultSettings.
+func New(opts ...Option) *Solver {
+	c := &Solver{}
+	c.initCredentialProviders()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.proc == nil {
+		c.proc = newProcess(DefaultSettings())
+		if c.log.GetSink() != nil {
+			c.proc.setLogger(c.log)
+		}
+	}
+	c.zoneLocks.serialize = c.proc.settings.SerializeZoneWrites
+	return c
+}
+
+// logger returns the solver's logger.
+func (c *Solver) logger() logr.Logger {
+	if c.log.GetSink() != nil {
+		return c.log
+	}
+	return c.proc.log
+}
+
+// baseContext returns a background context carrying the solver's logger.
+func (c *Solver) baseContext() context.Context {
+	return logr.NewContext(context.Background(), c.logger())
+}
+
+// Drain waits up to the DrainTimeout for in-flight Present and CleanUp
+// calls to finish. Call it after the webhook server returns.
+func (c *Solver) Drain() {
+	timeout := c.proc.settings.DrainTimeout
+	if !c.inflight.drain(timeout) {
+		c.logger().Info("WARNING: shutting down with challenge operations still running", "timeout", timeout)
+	}
+}
+
+// ChallengeKey identifies one TXT value. The fqdn alone isn't enough: a
+// certificate for both example.com and *.example.com gets two challenges
+// for _acme-challenge.example.com with different keys, and each value has
+// to be created and cleaned up on its own.
+type ChallengeKey struct {
+	fqdn string
+	key  string
+}
+
+func newChallengeKey(ch *v1alpha1.ChallengeRequest) ChallengeKey {
+	return ChallengeKey{fqdn: strings.ToLower(util.ToFqdn(ch.ResolvedFQDN)), key: ch.Key}
+}
+
+type trackedChallenge struct {
+	id uuid.UUID
+	// zone is the Nexus domain the record was created in, if known.
+	zone string
+	// presentedAt is zero for challenges recovered from old store entries.
+	presentedAt time.Time
+	// request is the ChallengeRequest that presented the record, if this
+	// replica presented it.
+	request *v1alpha1.ChallengeRequest
+	// backend is backendFallback if the record is in the fallback service.
+	backend string
+}
+
+// Config is the solver configuration an Issuer gives in its webhook config.
+type Config struct {
+	Service         string       `json:"service"`
+	ApiKeySecretRef secretKeyRef `json:"apikeysecret"`
+	// Encoding of the API key stored in the secret: "base64", "plain", or
+	// empty to use the decoded value if the key is valid base64.
+	Encoding string `json:"encoding,omitempty"`
+	// CredentialSource picks the credential provider. By default this is
+	// apikeysecret if set, and the webhook's ambient key otherwise.
+	CredentialSource credentialSource `json:"credentialSource,omitempty"`
+	Retry            retryConfig      `json:"retry,omitempty"`
+	// PropagationCheck, if set, delays Present until the record resolves.
+	PropagationCheck *propagationConfig `json:"propagationCheck,omitempty"`
+	// ZoneName, if set, is used as the Nexus domain instead of looking up
+	// the authoritative zone in public DNS.
+	ZoneName string `json:"zoneName,omitempty"`
+	// FollowCNAME creates the record at the end of any CNAME chain on the
+	// challenge name, for delegated challenges.
+	FollowCNAME bool `json:"followCNAME,omitempty"`
+	// ChallengeZone, if set, puts every record in this one delegated zone,
+	// named after the challenge it serves, e.g.
+	// _acme-challenge.example.com.acme.example.net. The certificate's own
+	// zone needs a matching CNAME but is never written to.
+	ChallengeZone string `json:"challengeZone,omitempty"`
+	// Zones overrides service and apikeysecret for particular zones, so one
+	// issuer can serve domains with different Nexus credentials.
+	Zones []zoneCredentials `json:"zones,omitempty"`
+	// APIVersion selects the Nexus challenge API to use. Only "v1" exists
+	// today; empty means v1.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// AllowedZones, if set, limits the zones records may be created in,
+	// on top of the webhook's --allowed-zones.
+	AllowedZones []string `json:"allowedZones,omitempty"`
+	// RecordNameTemplate, if set, is a Go template for the record name
+	// sent to Nexus, for setups that expect something other than the name
+	// relative to the zone. It can use .Record (that relative name), .Zone,
+	// .FQDN and .DNSName. Where the record is expected to resolve is
+	// unchanged.
+	RecordNameTemplate string `json:"recordNameTemplate,omitempty"`
+	// PresentTimeout and CleanupTimeout, if set, override --present-timeout
+	// and --cleanup-timeout for this issuer.
+	PresentTimeout *metav1.Duration `json:"presentTimeout,omitempty"`
+	CleanupTimeout *metav1.Duration `json:"cleanupTimeout,omitempty"`
+	// Fallback, if set, is a second service Present uses when creating the
+	// record in the primary one fails. CleanUp deletes the record from
+	// whichever service holds it.
+	Fallback *fallbackService `json:"fallback,omitempty"`
+
+	// Delegate, if set, is a DNS01 webhook that gets the challenges for
+	// names outside zoneName, zones and allowedZones, instead of Nexus.
+	Delegate *delegateSolver `json:"delegate,omitempty"`
+}
+
+const (
+	encodingBase64 = "base64"
+	encodingPlain  = "plain"
+)
+
+func (c *Solver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	settings := &c.proc.settings
+	c.logger().Info("starting", "version", version, "commit", gitCommit, "buildDate", buildDate)
+
+	if _, err := c.config(nil); err != nil {
+		return fmt.Errorf("invalid default config for solver %s: %w", c.Name(), err)
+	}
+
+	var err error
+	if c.client == nil {
+		c.client, err = kubernetes.NewForConfig(kubeClientConfig)
+		if err != nil {
+			return err
+		}
+	}
+	cl := c.client
+	c.clients.ttl = settings.ClientCacheTTL
+	if c.credentials == nil {
+		c.initCredentialProviders()
+	}
+
+	if settings.SecretInformer {
+		c.secrets = newSecretLister(cl, stopCh)
+		c.secrets.onRotate = c.secretRotated
+	}
+
+	if settings.EmitEvents {
+		c.events, err = newEventRecorder(c.proc, kubeClientConfig, cl, stopCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The RBAC preflight, tenant policy, audit log, tracing, cluster
+	// defaults, SIGHUP handling and the metrics, health, pprof and debug
+	// servers are shared by every solver in the process.
+	if err = c.proc.start(cl, stopCh); err != nil {
+		return err
+	}
+	c.proc.register(c)
+
+	switch {
+	case c.store != nil:
+		// Set with WithStateStore.
+	case settings.StateCRD:
+		dc, err := dynamic.NewForConfig(kubeClientConfig)
+		if err != nil {
+			return err
+		}
+		c.store = &crdStore{client: dc, namespace: settings.StateNamespace}
+	case settings.StateConfigMap != "":
+		c.store = &configMapStore{client: cl, namespace: settings.StateNamespace, name: settings.StateConfigMap}
+	}
+
+	if settings.OrphanGCInterval > 0 {
+		if c.store == nil {
+			return errors.New("--orphan-gc-interval requires --state-configmap or --state-crd")
+		}
+		challenges, err := c.proc.challengeLister(kubeClientConfig, stopCh)
+		if err != nil {
+			return err
+		}
+		c.startOrphanGC(challenges, settings.OrphanGCInterval, stopCh)
+	}
+	if settings.RecordVerifyInterval > 0 {
+		c.startRecordVerifier(settings.RecordVerifyInterval, stopCh)
+	}
+	if settings.IssuerDefaults {
+		challenges, err := c.proc.challengeLister(kubeClientConfig, stopCh)
+		if err != nil {
+			return err
+		}
+		dyn, err := dynamic.NewForConfig(kubeClientConfig)
+		if err != nil {
+			return err
+		}
+		c.issuerDefaults = &issuerDefaults{proc: c.proc, challenges: challenges, dynamic: dyn}
+	}
+
+	health := &c.proc.health
+	go func() {
+		<-stopCh
+		health.setUnready()
+		c.inflight.drain(settings.DrainTimeout)
+	}()
+
+	if settings.StartupCredentialCheck {
+		return c.awaitCredentials(health, stopCh)
+	}
+	health.setReady()
+	return nil
+}
+
+func (c *Solver) Name() string {
+	if c.name != "" {
+		return c.name
+	}
+	return defaultName
+}
+
+func (c *Solver) Present(ch *v1alpha1.ChallengeRequest) (err error) {
+	done, err := c.inflight.begin()
+	if err != nil {
+		return
+	}
+	defer done()
+
+	start := time.Now()
+	ctx := withRequestID(c.baseContext(), ch)
+	defer func() {
+		observeOperation(opPresent, start, err)
+		c.recordFailure(ctx, ch, reasonPresentFailed, err)
+		err = withFailureReason(ctx, ch, err)
+	}()
+	ctx, span := tracer.Start(ctx, "Present", challengeAttributes(ctx, ch))
+	defer func() { endSpan(span, err) }()
+
+	cfg, err := c.challengeConfig(ctx, ch)
+	if err != nil {
+		return
+	}
+	if err = c.validate(&cfg, ch.AllowAmbientCredentials); err != nil {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, cfg.PresentTimeout, c.proc.settings.PresentTimeout)
+	defer cancel()
+	if cfg.delegated(ch) {
+		err = c.delegate(ctx, cfg.Delegate, ch, v1alpha1.ChallengeActionPresent)
+		return
+	}
+	target, err := c.resolveTarget(ctx, ch, &cfg)
+	if err != nil {
+		return
+	}
+	if err = c.proc.checkZonePolicy(target.fqdn, &cfg); err != nil {
+		return
+	}
+	log := challengeLogger(ctx, ch).WithValues("record", target.record, "domain", target.domain)
+	nc, err := c.nexusApiClient(ctx, ch, &cfg, target.domain)
+	if err != nil {
+		return
+	}
+	if err = c.proc.tenants.check(ch.ResourceNamespace, target.fqdn, cfg.Service); err != nil {
+		return
+	}
+
+	ck := newChallengeKey(ch)
+	call, leader := c.presents.join(ck)
+	if !leader {
+		log.V(logf.InfoLevel).Info("waiting for the Present already in flight for this challenge")
+		return call.wait(ctx)
+	}
+	defer func() { c.presents.finish(ck, call, err) }()
+	defer c.challengeLocks.acquire(ck)()
+	defer c.markActive(ck, statePresenting)()
+
+	if tc, ok := c.lookupChallenge(ctx, ck); ok {
+		log.V(logf.InfoLevel).Info("record already presented", "challengeId", tc.id)
+		return c.awaitPropagation(ctx, ch, &cfg, target, log)
+	}
+
+	if err = c.budgets.check(ck, cfg.Retry); err != nil {
+		return
+	}
+	log.V(logf.DebugLevel).Info("presenting record")
+
+	var backend string
+	challengeId, err := c.createRecord(ctx, &cfg, nc, ch, target, log)
+	if err != nil && cfg.Fallback != nil && ctx.Err() == nil {
+		nexusErrorsTotal.WithLabelValues(opPresent).Inc()
+		log.Error(err, "failed to create challenge record, trying the fallback service", "fallbackService", cfg.Fallback.Service)
+		backend = backendFallback
+		if nc, err = c.backendClient(ctx, ch, &cfg, target.domain, backend); err != nil {
+			return
+		}
+		if err = c.proc.tenants.check(ch.ResourceNamespace, target.fqdn, cfg.Service); err != nil {
+			return
+		}
+		challengeId, err = c.createRecord(ctx, &cfg, nc, ch, target, log)
+		if err == nil {
+			failoversTotal.Inc()
+		}
+	}
+	if err != nil {
+		nexusErrorsTotal.WithLabelValues(opPresent).Inc()
+		log.Error(err, "failed to create challenge record", "duration", time.Since(start))
+		if !errors.Is(err, context.Canceled) {
+			c.budgets.fail(ck, err)
+		}
+		return err
+	}
+	c.budgets.reset(ck)
+	log.Info("presented record", "challengeId", challengeId, "backend", backend, "duration", time.Since(start))
+	tc := trackedChallenge{id: challengeId, zone: target.domain, presentedAt: time.Now(), request: ch, backend: backend}
+	if held := c.trackChallenge(ctx, ck, tc, ch); held.id != tc.id {
+		// Another replica presented the same challenge first. Keep its
+		// record, and don't leave a second one behind.
+		log.V(logf.InfoLevel).Info("record already presented by another replica", "challengeId", held.id)
+		if err = c.deleteRecord(ctx, &cfg, nc, ch, target, tc.id, log); err != nil {
+			log.Error(err, "failed to delete duplicate challenge record", "challengeId", tc.id)
+			err = nil
+		}
+	}
+	return c.awaitPropagation(ctx, ch, &cfg, target, log)
+}
+
+func (c *Solver) awaitPropagation(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config, target challengeTarget, log logr.Logger) error {
+	if cfg.PropagationCheck == nil {
+		return nil
+	}
+	return waitForPropagation(ctx, c.proc.family(), target.fqdn, ch.Key, cfg.PropagationCheck, log)
+}
+
+func (c *Solver) CleanUp(ch *v1alpha1.ChallengeRequest) (err error) {
+	done, err := c.inflight.begin()
+	if err != nil {
+		return
+	}
+	defer done()
+
+	start := time.Now()
+	ctx := withRequestID(c.baseContext(), ch)
+	defer func() {
+		observeOperation(opCleanUp, start, err)
+		c.recordFailure(ctx, ch, reasonCleanUpFailed, err)
+		err = withFailureReason(ctx, ch, err)
+	}()
+	log := challengeLogger(ctx, ch)
+
+	ctx, span := tracer.Start(ctx, "CleanUp", challengeAttributes(ctx, ch))
+	defer func() { endSpan(span, err) }()
+
+	cfg, err := c.challengeConfig(ctx, ch)
+	if err != nil {
+		return
+	}
+	if err = c.validate(&cfg, ch.AllowAmbientCredentials); err != nil {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, cfg.CleanupTimeout, c.proc.settings.CleanupTimeout)
+	defer cancel()
+	if cfg.delegated(ch) {
+		err = c.delegate(ctx, cfg.Delegate, ch, v1alpha1.ChallengeActionCleanUp)
+		return
+	}
+	target, err := c.resolveTarget(ctx, ch, &cfg)
+	if err != nil {
+		return
+	}
+
+	ck := newChallengeKey(ch)
+	defer c.challengeLocks.acquire(ck)()
+	defer c.markActive(ck, stateCleaningUp)()
+	c.budgets.reset(ck)
+
+	tc, ok := c.lookupChallenge(ctx, ck)
+	if !ok {
+		c.reportUntrackedRecord(ctx, ch)
+		return
+	}
+	nc, err := c.backendClient(ctx, ch, &cfg, target.domain, tc.backend)
+	if err != nil {
+		return
+	}
+
+	log = log.WithValues("challengeId", tc.id)
+	log.V(logf.DebugLevel).Info("cleaning up record")
+
+	if err = c.deleteRecord(ctx, &cfg, nc, ch, target, tc.id, log); err != nil {
+		nexusErrorsTotal.WithLabelValues(opCleanUp).Inc()
+		log.Error(err, "failed to delete challenge record", "duration", time.Since(start))
+		return
+	}
+	log.Info("cleaned up record", "duration", time.Since(start))
+	if !tc.presentedAt.IsZero() {
+		challengeLifetime.Observe(time.Since(tc.presentedAt).Seconds())
+	}
+	c.forgetChallenge(ctx, ck)
+	return
+}
+
+// createRecord asks Nexus for ch's TXT record at target, retrying as cfg
+// allows where that can't leave a duplicate, and audits the outcome. It
+// waits for other writes to the zone.
+func (c *Solver) createRecord(ctx context.Context, cfg *Config, nc nexusclient.API, ch *v1alpha1.ChallengeRequest, target challengeTarget, log logr.Logger) (id uuid.UUID, err error) {
+	defer c.zoneLocks.acquire(target.domain)()
+	err = c.withRetryHint(ctx, cfg.Service+"/"+target.domain, cfg.Retry, cfg.Retry.retryableCreate, log, func() (err error) {
+		_, nexusSpan := tracer.Start(ctx, "nexus.CreateChallengeRecord", requestAttributes(ctx))
+		callStart := time.Now()
+		id, err = callNexusLate(ctx, c.proc, func() (uuid.UUID, error) {
+			return nc.CreateChallengeRecord(target.record, ch.Key)
+		}, func(id uuid.UUID, err error) {
+			if err == nil {
+				c.deleteAbandonedRecord(ctx, *cfg, nc, ch, target, id, log)
+			}
+		})
+		observeNexusCall(endpointCreate, target.domain, callStart, err)
+		endSpan(nexusSpan, err)
+		return
+	})
+	c.proc.streaks.observe(opPresent, target.domain, err, log)
+	c.auditMutation(ctx, auditCreate, ch, target, id, err)
+	return
+}
+
+// deleteAbandonedRecord deletes record id, which a create Present stopped
+// waiting for made after all. Present has failed or presented another
+// record by now, so nothing else knows id and Nexus can't list records.
+func (c *Solver) deleteAbandonedRecord(ctx context.Context, cfg Config, nc nexusclient.API, ch *v1alpha1.ChallengeRequest, target challengeTarget, id uuid.UUID, log logr.Logger) {
+	ctx = context.WithoutCancel(ctx)
+	log = log.WithValues("challengeId", id)
+	c.auditMutation(ctx, auditCreate, ch, target, id, nil)
+	log.Info("deleting the record made by an abandoned create")
+	if err := c.deleteRecord(ctx, &cfg, nc, ch, target, id, log); err != nil {
+		log.Error(err, "WARNING: could not delete the record made by an abandoned create; remove it manually")
+	}
+}
+
+// deleteRecord asks Nexus to delete record id, retrying as cfg allows, and
+// audits the outcome.
+func (c *Solver) deleteRecord(ctx context.Context, cfg *Config, nc nexusclient.API, ch *v1alpha1.ChallengeRequest, target challengeTarget, id uuid.UUID, log logr.Logger) (err error) {
+	defer c.zoneLocks.acquire(target.domain)()
+	err = c.withRetryHint(ctx, cfg.Service+"/"+target.domain, cfg.Retry, cfg.Retry.retryable, log, func() (err error) {
+		_, nexusSpan := tracer.Start(ctx, "nexus.DeleteChallengeRecord", requestAttributes(ctx))
+		callStart := time.Now()
+		_, err = callNexus(ctx, c.proc, func() (struct{}, error) {
+			return struct{}{}, nc.DeleteChallengeRecord(id)
+		})
+		observeNexusCall(endpointDelete, target.domain, callStart, err)
+		endSpan(nexusSpan, err)
+		return
+	})
+	c.proc.streaks.observe(opCleanUp, target.domain, err, log)
+	c.auditMutation(ctx, auditDelete, ch, target, id, err)
+	return
+}
+
+func (c *Solver) recordFailure(ctx context.Context, ch *v1alpha1.ChallengeRequest, reason string, err error) {
+	if err == nil || c.events == nil {
+		return
+	}
+	c.events.failure(ctx, ch, reason, err)
+}
+
+// lookupChallenge finds the record tracked for ck. With a store, the store
+// is authoritative, since other replicas may have presented or cleaned up
+// the challenge; the in-memory copy is only used if the store can't be read.
+func (c *Solver) lookupChallenge(ctx context.Context, ck ChallengeKey) (tc trackedChallenge, ok bool) {
+	c.lock.Lock()
+	tc, ok = c.challenges[ck]
+	c.lock.Unlock()
+	if c.store == nil {
+		return
+	}
+
+	ctx, cancel := c.proc.withKubeTimeout(ctx)
+	defer cancel()
+	sc, stored, err := c.store.Get(ctx, ck)
+	if err != nil {
+		contextLogger(ctx).Error(err, "could not read stored challenge", "fqdn", ck.fqdn)
+		return
+	}
+	if !stored {
+		c.lock.Lock()
+		delete(c.challenges, ck)
+		c.lock.Unlock()
+		return trackedChallenge{}, false
+	}
+	return trackedChallenge{id: sc.ID, zone: sc.Zone, presentedAt: sc.PresentedAt, request: sc.Request, backend: sc.Backend}, true
+}
+
+// trackChallenge records tc for ck and returns the record that ends up
+// tracked, which is another replica's if it stored one first.
+func (c *Solver) trackChallenge(ctx context.Context, ck ChallengeKey, tc trackedChallenge, ch *v1alpha1.ChallengeRequest) trackedChallenge {
+	if c.store != nil {
+		storeCtx, cancel := c.proc.withKubeTimeout(ctx)
+		sc := StoredChallenge{ID: tc.id, PresentedAt: tc.presentedAt, Request: ch, Solver: c.Name(), Zone: tc.zone, Backend: tc.backend}
+		held, err := c.store.Claim(storeCtx, ck, sc)
+		cancel()
+		if err != nil {
+			contextLogger(ctx).Error(err, "could not persist challenge", "fqdn", ck.fqdn)
+		} else {
+			tc = trackedChallenge{id: held.ID, zone: tc.zone, presentedAt: held.PresentedAt, request: tc.request, backend: held.Backend}
+		}
+	}
+
+	c.lock.Lock()
+	if c.challenges == nil {
+		c.challenges = make(map[ChallengeKey]trackedChallenge)
+	}
+	c.challenges[ck] = tc
+	c.lock.Unlock()
+	return tc
+}
+
+func (c *Solver) forgetChallenge(ctx context.Context, ck ChallengeKey) {
+	c.lock.Lock()
+	delete(c.challenges, ck)
+	c.lock.Unlock()
+
+	if c.store != nil {
+		ctx, cancel := c.proc.withKubeTimeout(ctx)
+		defer cancel()
+		if err := c.store.Delete(ctx, ck); err != nil {
+			contextLogger(ctx).Error(err, "could not remove stored challenge", "fqdn", ck.fqdn)
+		}
+	}
+}
+
+// reportUntrackedRecord is called when CleanUp has no record ID for a
+// challenge. The Nexus client can only delete records by ID, so if the
+// record is still being served we can't remove it ourselves; make sure the
+// orphan is at least visible to operators.
+func (c *Solver) reportUntrackedRecord(ctx context.Context, ch *v1alpha1.ChallengeRequest) {
+	log := challengeLogger(ctx, ch)
+	family := c.proc.family()
+	live, err := family.preCheckDNS(ctx, ch.ResolvedFQDN, ch.Key, family.recursiveNameservers(), true)
+	if err != nil {
+		log.Error(err, "no record tracked, and could not check whether it is still served")
+		return
+	}
+	if !live {
+		log.V(logf.InfoLevel).Info("no record tracked, nothing to clean up")
+		return
+	}
+	log.Info("WARNING: orphaned TXT record is still served but its ID is unknown; remove it manually",
+		"value", ch.Key)
+}
+
+// challengeLogger returns a logger annotated with the fields identifying a
+// challenge request.
+func challengeLogger(ctx context.Context, ch *v1alpha1.ChallengeRequest) logr.Logger {
+	return contextLogger(ctx).WithValues(
+		"fqdn", ch.ResolvedFQDN,
+		"zone", ch.ResolvedZone,
+		"namespace", ch.ResourceNamespace,
+	)
+}
+
+// challengeAttributes returns span attributes identifying a challenge request.
+func challengeAttributes(ctx context.Context, ch *v1alpha1.ChallengeRequest) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String("request.id", requestIDFrom(ctx)),
+		attribute.String("dns.fqdn", ch.ResolvedFQDN),
+		attribute.String("dns.zone", ch.ResolvedZone),
+		attribute.String("k8s.namespace.name", ch.ResourceNamespace),
+	)
+}
+
+// config decodes a challenge's config on top of the solver's defaults,
+// which are on top of the cluster defaults.
+func (c *Solver) config(cfgJSON *extapi.JSON) (cfg Config, err error) {
+	return c.layeredConfig(nil, cfgJSON)
+}
+
+// layeredConfig is config with issuer's fields, if any, between the
+// solver's defaults and cfgJSON.
+func (c *Solver) layeredConfig(issuer json.RawMessage, cfgJSON *extapi.JSON) (cfg Config, err error) {
+	fields := map[string]json.RawMessage{}
+	c.proc.cluster.apply(fields)
+	if c.defaults != nil {
+		if err = json.Unmarshal(c.defaults, &fields); err != nil {
+			err = fmt.Errorf("error decoding default config: %w", err)
+			return
+		}
+	}
+	if len(issuer) > 0 {
+		if err = json.Unmarshal(issuer, &fields); err != nil {
+			err = fmt.Errorf("error decoding issuer defaults: %w", err)
+			return
+		}
+	}
+	if cfgJSON != nil {
+		if err = json.Unmarshal(cfgJSON.Raw, &fields); err != nil {
+			err = fmt.Errorf("error decoding solver config: %w", err)
+			return
+		}
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	if cfg, err = loadConfig(&extapi.JSON{Raw: raw}); err != nil {
+		return
+	}
+	err = cfg.expandEnv(c.proc.settings.ConfigEnvPrefix)
+	return
+}
+
+func loadConfig(cfgJSON *extapi.JSON) (cfg Config, err error) {
+	cfg = Config{}
+	if cfgJSON == nil {
+		return
+	}
+	err = json.Unmarshal(cfgJSON.Raw, &cfg)
+	if err != nil {
+		err = fmt.Errorf("error decoding solver config: %w", err)
+		return
+	}
+
+	// Reject typos rather than silently running with defaults.
+	var raw interface{}
+	if err = json.Unmarshal(cfgJSON.Raw, &raw); err != nil {
+		return
+	}
+	if unknown := unknownFields(raw, reflect.TypeOf(cfg), ""); len(unknown) > 0 {
+		err = fmt.Errorf("unknown fields in solver config: %s", strings.Join(unknown, ", "))
+	}
+	return
+}
+
+func (c *Solver) nexusApiClient(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config, domainName string) (client nexusclient.API, err error) {
+	cfg.applyZoneCredentials(domainName)
+	keyStr, err := c.apiKey(ctx, ch, cfg)
+	if err != nil {
+		secretFailuresTotal.WithLabelValues(secretFailureReason(err)).Inc()
+		return
+	}
+	buf := keyBuffers.Get().(*[]byte)
+	defer releaseKeyBuffer(buf)
+	key, err := decodeKey((*buf)[:0], keyStr, cfg.Encoding)
+	if err != nil {
+		secretFailuresTotal.WithLabelValues(secretReasonDecode).Inc()
+		return
+	}
+	*buf = key
+	contextLogger(ctx).V(logf.DebugLevel).Info("getting nexus client",
+		"domain", domainName, "service", cfg.Service,
+		"namespace", ch.ResourceNamespace, "secret", cfg.ApiKeySecretRef.Name)
+	newClient := c.newClient
+	if newClient == nil {
+		newClient = nexusclient.Versions[cfg.apiVersion()]
+	}
+	client, err = c.clients.get(cfg.apiVersion(), domainName, cfg.Service, key, func() (nexusclient.API, error) {
+		// The client keeps its own copy; ours is wiped on return.
+		return newClient(domainName, cfg.Service, bytes.Clone(key))
+	})
+	return
+}
+
+// decodeKey turns the API key read from a secret into raw key bytes,
+// appended to dst.
+func decodeKey(dst []byte, keyStr, encoding string) ([]byte, error) {
+	keyStr = strings.TrimSpace(keyStr)
+	switch encoding {
+	case encodingBase64:
+		key, err := decodeBase64Key(dst, keyStr)
+		if err != nil {
+			return nil, fmt.Errorf("failure to decode base64 secret: %w", err)
+		}
+		return key, nil
+	case encodingPlain:
+		return append(dst, keyStr...), nil
+	case "":
+		if key, err := decodeBase64Key(dst, keyStr); err == nil {
+			return key, nil
+		}
+		return append(dst, keyStr...), nil
+	default:
+		return nil, fmt.Errorf("unknown key encoding %q, expected %q or %q", encoding, encodingBase64, encodingPlain)
+	}
+}
+
+// validate checks loaded config for mistakes cert-manager should report back
+// on the Challenge, rather than letting them surface as Nexus errors.
+func (c *Solver) validate(cfg *Config, allowAmbientCredentials bool) error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if cfg.Service == "" && !cfg.zonesSetService() {
+		problem("service is required")
+	}
+	if _, ok := nexusclient.Versions[cfg.apiVersion()]; !ok {
+		problem("unsupported apiVersion %q", cfg.APIVersion)
+	}
+	switch cfg.Encoding {
+	case "", encodingBase64, encodingPlain:
+	default:
+		problem("encoding must be %q or %q, got %q", encodingBase64, encodingPlain, cfg.Encoding)
+	}
+
+	ref := cfg.ApiKeySecretRef
+	if ref.Name != "" && ref.Key == "" {
+		problem("apikeysecret.key is required when apikeysecret.name is set")
+	}
+	if !ref.set() && ref.Key != "" {
+		problem("apikeysecret.name or apikeysecret.selector is required when apikeysecret.key is set")
+	}
+	if ref.Selector != nil {
+		switch {
+		case ref.Name != "":
+			problem("apikeysecret.name and apikeysecret.selector can't both be set")
+		case ref.Key == "":
+			problem("apikeysecret.key is required when apikeysecret.selector is set")
+		}
+		if selector, err := metav1.LabelSelectorAsSelector(ref.Selector); err != nil {
+			problem("apikeysecret.selector: %v", err)
+		} else if selector.Empty() {
+			problem("apikeysecret.selector must match at least one label")
+		}
+	}
+	name := cfg.providerName(c.proc.settings.APIKeyFile)
+	if provider, ok := c.credentials[name]; !ok {
+		problem("unknown credentialSource.provider %q", name)
+	} else if provider.Ambient() && !allowAmbientCredentials && len(cfg.Zones) == 0 {
+		problem("apikeysecret is required, since ambient credentials are not allowed for this issuer")
+	}
+	if cfg.CredentialSource.Vault != nil {
+		if err := cfg.CredentialSource.Vault.validate(); err != nil {
+			problem("credentialSource.vault: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, z := range cfg.Zones {
+		zone := strings.ToLower(util.UnFqdn(z.Zone))
+		switch {
+		case zone == "":
+			problem("zones[%d].zone is required", i)
+		case seen[zone]:
+			problem("zones[%d]: zone %s is listed more than once", i, z.Zone)
+		}
+		seen[zone] = true
+		if !z.ApiKeySecretRef.set() || z.ApiKeySecretRef.Key == "" {
+			problem("zones[%d].apikeysecret needs a name or selector, and a key", i)
+		}
+	}
+
+	if cfg.RecordNameTemplate != "" {
+		if _, err := parseRecordNameTemplate(cfg.RecordNameTemplate); err != nil {
+			problem("recordNameTemplate: %v", err)
+		}
+	}
+
+	if cfg.ChallengeZone != "" && (cfg.ZoneName != "" || cfg.FollowCNAME) {
+		problem("challengeZone can't be combined with zoneName or followCNAME")
+	}
+
+	r := cfg.Retry
+	if r.MaxAttempts < 0 {
+		problem("retry.maxAttempts must not be negative")
+	}
+	if r.InitialBackoff != nil && r.InitialBackoff.Duration <= 0 {
+		problem("retry.initialBackoff must be positive")
+	}
+	if r.MaxBackoff != nil && r.InitialBackoff != nil && r.MaxBackoff.Duration < r.InitialBackoff.Duration {
+		problem("retry.maxBackoff must be at least retry.initialBackoff")
+	}
+	if r.ChallengeAttempts < 0 {
+		problem("retry.challengeAttempts must not be negative")
+	}
+	if r.ChallengeTimeout != nil && r.ChallengeTimeout.Duration <= 0 {
+		problem("retry.challengeTimeout must be positive")
+	}
+	if f := cfg.Fallback; f != nil {
+		if f.Service == "" {
+			problem("fallback.service is required")
+		}
+		ref := f.ApiKeySecretRef
+		if (ref.Name != "" || ref.Selector != nil || ref.Key != "") && (ref.Key == "" || (ref.Name == "") == (ref.Selector == nil)) {
+			problem("fallback.apikeysecret needs a name or selector, and a key")
+		}
+	}
+	if d := cfg.Delegate; d != nil {
+		if errs := validation.IsDNS1123Subdomain(d.GroupName); len(errs) > 0 {
+			problem("delegate.groupName %q is not an API group: %s", d.GroupName, strings.Join(errs, "; "))
+		}
+		if d.SolverName == "" {
+			problem("delegate.solverName is required")
+		}
+		if d.GroupName == c.groupName && d.SolverName == c.Name() {
+			problem("delegate can't be this solver")
+		}
+		if cfg.ZoneName == "" && len(cfg.Zones) == 0 && len(cfg.AllowedZones) == 0 {
+			problem("delegate needs zoneName, zones or allowedZones to tell which names Nexus manages")
+		}
+	}
+	if p := cfg.PropagationCheck; p != nil {
+		if p.timeout() <= 0 || p.interval() <= 0 {
+			problem("propagationCheck.timeout and interval must be positive")
+		}
+		switch p.Mode {
+		case "", propagationAuthoritative, propagationRecursive, propagationDoH:
+		default:
+			problem("propagationCheck.mode must be %q, %q or %q, got %q", propagationAuthoritative, propagationRecursive, propagationDoH, p.Mode)
+		}
+		if p.DoHURL != "" {
+			if p.Mode != propagationDoH {
+				problem("propagationCheck.dohURL needs mode %q", propagationDoH)
+			}
+			if _, err := dohEndpoint(p.DoHURL); err != nil {
+				problem("propagationCheck.dohURL: %v", err)
+			}
+		}
+		for _, ns := range p.Nameservers {
+			if _, _, err := net.SplitHostPort(ns); err != nil {
+				problem("propagationCheck.nameservers: %q must be host:port", ns)
+			}
+		}
+	}
+	if cfg.PresentTimeout != nil && cfg.PresentTimeout.Duration <= 0 {
+		problem("presentTimeout must be positive")
+	}
+	if cfg.CleanupTimeout != nil && cfg.CleanupTimeout.Duration <= 0 {
+		problem("cleanupTimeout must be positive")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid solver config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// challengeTarget is where a challenge's TXT record is created: the Nexus
+// domain, and the record name within it.
+type challengeTarget struct {
+	fqdn   string
+	domain string
+	record string
+}
+
+func (c *Solver) resolveTarget(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg *Config) (t challengeTarget, err error) {
+	defer func() {
+		if err == nil && cfg.RecordNameTemplate != "" {
+			t.record, err = renderRecordName(cfg.RecordNameTemplate, t, ch)
+		}
+	}()
+
+	if cfg.ChallengeZone != "" {
+		t.domain = util.UnFqdn(cfg.ChallengeZone)
+		t.record = util.UnFqdn(ch.ResolvedFQDN)
+		t.fqdn = util.ToFqdn(t.record + "." + t.domain)
+		return
+	}
+
+	t.fqdn = ch.ResolvedFQDN
+	if cfg.FollowCNAME {
+		t.fqdn, err = util.DNS01LookupFQDN(ctx, ch.DNSName, true, c.proc.family().recursiveNameservers()...)
+		if err != nil {
+			err = fmt.Errorf("failed to follow CNAMEs for %s: %w", ch.ResolvedFQDN, err)
+			return
+		}
+	}
+
+	t.domain = util.UnFqdn(cfg.ZoneName)
+	if t.domain == "" {
+		zone := ch.ResolvedZone
+		if t.fqdn != ch.ResolvedFQDN {
+			zone = t.fqdn
+		}
+		t.domain = c.extractDomainName(ctx, zone)
+	}
+	if !strings.HasSuffix(strings.ToLower(t.fqdn), "."+strings.ToLower(util.ToFqdn(t.domain))) {
+		err = fmt.Errorf("%w: %s is not under %s", ErrZoneMismatch, t.fqdn, t.domain)
+		return
+	}
+
+	t.record = extractRecordName(t.fqdn, t.domain)
+	return
+}
+
+// recordNameVars are the values a recordNameTemplate can use.
+type recordNameVars struct {
+	Record  string
+	Zone    string
+	FQDN    string
+	DNSName string
+}
+
+func parseRecordNameTemplate(text string) (*template.Template, error) {
+	return template.New("recordNameTemplate").Option("missingkey=error").Parse(text)
+}
+
+// renderRecordName expands a recordNameTemplate for t.
+func renderRecordName(text string, t challengeTarget, ch *v1alpha1.ChallengeRequest) (string, error) {
+	tmpl, err := parseRecordNameTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid recordNameTemplate: %w", err)
+	}
+	var name strings.Builder
+	vars := recordNameVars{Record: t.record, Zone: t.domain, FQDN: util.UnFqdn(t.fqdn), DNSName: ch.DNSName}
+	if err := tmpl.Execute(&name, vars); err != nil {
+		return "", fmt.Errorf("failed to expand recordNameTemplate: %w", err)
+	}
+	if strings.TrimSpace(name.String()) == "" {
+		return "", fmt.Errorf("recordNameTemplate expanded to an empty name for %s", t.fqdn)
+	}
+	return name.String(), nil
+}
+
+func extractRecordName(fqdn, domain string) string {
+	name := util.UnFqdn(fqdn)
+	if idx := strings.Index(name, "."+util.UnFqdn(domain)); idx != -1 {
+		return name[:idx]
+	}
+	return name
+}
+
+func (c *Solver) extractDomainName(ctx context.Context, zone string) string {
+	_, span := tracer.Start(ctx, "FindZoneByFqdn", trace.WithAttributes(attribute.String("dns.zone", zone)))
+	authZone, err := c.proc.zones.find(ctx, zone)
+	endSpan(span, err)
+	if err != nil {
+		contextLogger(ctx).Error(err, "could not get zone by fqdn", "zone", zone)
+		return zone
+	}
+	return util.UnFqdn(authZone)
+}
@@ -0,0 +1,421 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+
+	"github.com/fudoniten/cert-manager-webhook-nexus/internal/nexusclient"
+	"github.com/fudoniten/cert-manager-webhook-nexus/nexustest"
+)
+
+func TestDecodeKey(t *testing.T) {
+	tests := []struct {
+		key, encoding, expected string
+		fails                   bool
+	}{
+		{key: "c2VjcmV0", encoding: "base64", expected: "secret"},
+		{key: "c2VjcmV0\n", encoding: "", expected: "secret"},
+		{key: "c2VjcmV0", encoding: "plain", expected: "c2VjcmV0"},
+		{key: "not base64!", encoding: "", expected: "not base64!"},
+		{key: "not base64!", encoding: "base64", fails: true},
+		{key: "secret", encoding: "hex", fails: true},
+	}
+	for _, test := range tests {
+		key, err := decodeKey(nil, test.key, test.encoding)
+		if test.fails {
+			if err == nil {
+				t.Errorf("decodeKey(%q, %q): expected an error", test.key, test.encoding)
+			}
+			continue
+		}
+		if err != nil || string(key) != test.expected {
+			t.Errorf("decodeKey(%q, %q) = %q, %v; expected %q", test.key, test.encoding, key, err, test.expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := New(WithGroupName("acme.example.com"))
+	secret := secretKeyRef{SecretKeySelector: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "nexus"}, Key: "key"}}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		ambient bool
+		valid   bool
+	}{
+		{"secret", Config{Service: "svc", ApiKeySecretRef: secret}, false, true},
+		{"ambient", Config{Service: "svc"}, true, true},
+		{"ambient not allowed", Config{Service: "svc"}, false, false},
+		{"no service", Config{ApiKeySecretRef: secret}, false, false},
+		{"no secret key", Config{Service: "svc", ApiKeySecretRef: secretKeyRef{SecretKeySelector: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "nexus"},
+		}}}, false, false},
+		{"bad encoding", Config{Service: "svc", ApiKeySecretRef: secret, Encoding: "hex"}, false, false},
+		{"selector", Config{Service: "svc", ApiKeySecretRef: secretKeyRef{
+			SecretKeySelector: corev1.SecretKeySelector{Key: "key"},
+			Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"nexus.fudo.org/zone": "example.com"}},
+		}}, false, true},
+		{"empty selector", Config{Service: "svc", ApiKeySecretRef: secretKeyRef{
+			SecretKeySelector: corev1.SecretKeySelector{Key: "key"},
+			Selector:          &metav1.LabelSelector{},
+		}}, false, false},
+		{"selector and name", Config{Service: "svc", ApiKeySecretRef: secretKeyRef{
+			SecretKeySelector: secret.SecretKeySelector,
+			Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"nexus.fudo.org/zone": "example.com"}},
+		}}, false, false},
+		{"zones", Config{Zones: []zoneCredentials{
+			{Zone: "example.com", Service: "svc", ApiKeySecretRef: secret},
+		}}, false, true},
+		{"duplicate zones", Config{Service: "svc", Zones: []zoneCredentials{
+			{Zone: "example.com", ApiKeySecretRef: secret},
+			{Zone: "example.com.", ApiKeySecretRef: secret},
+		}}, false, false},
+		{"challengeZone with zoneName", Config{
+			Service: "svc", ApiKeySecretRef: secret, ZoneName: "example.com", ChallengeZone: "acme.example.net",
+		}, false, false},
+		{"negative backoff", Config{Service: "svc", ApiKeySecretRef: secret, Retry: retryConfig{
+			InitialBackoff: &metav1.Duration{Duration: -time.Second},
+		}}, false, false},
+		{"fallback", Config{Service: "svc", ApiKeySecretRef: secret, Fallback: &fallbackService{Service: "svc-dr", ApiKeySecretRef: secret}}, false, true},
+		{"fallback without service", Config{Service: "svc", ApiKeySecretRef: secret, Fallback: &fallbackService{ApiKeySecretRef: secret}}, false, false},
+		{"delegate", Config{Service: "svc", ApiKeySecretRef: secret, ZoneName: "example.com", Delegate: &delegateSolver{GroupName: "acme.other.example", SolverName: "route53"}}, false, true},
+		{"delegate without managed zones", Config{Service: "svc", ApiKeySecretRef: secret, Delegate: &delegateSolver{GroupName: "acme.other.example", SolverName: "route53"}}, false, false},
+		{"delegate to itself", Config{Service: "svc", ApiKeySecretRef: secret, ZoneName: "example.com", Delegate: &delegateSolver{GroupName: "acme.example.com", SolverName: "nexus"}}, false, false},
+		{"delegate without solver", Config{Service: "svc", ApiKeySecretRef: secret, ZoneName: "example.com", Delegate: &delegateSolver{GroupName: "acme.other.example"}}, false, false},
+		{"bad propagation mode", Config{Service: "svc", ApiKeySecretRef: secret, PropagationCheck: &propagationConfig{Mode: "dig"}}, false, false},
+		{"doh resolver", Config{Service: "svc", ApiKeySecretRef: secret, PropagationCheck: &propagationConfig{Mode: propagationDoH, DoHURL: "google"}}, false, true},
+		{"doh resolver over http", Config{Service: "svc", ApiKeySecretRef: secret, PropagationCheck: &propagationConfig{Mode: propagationDoH, DoHURL: "http://dns.example.com/dns-query"}}, false, false},
+		{"dohURL outside doh mode", Config{Service: "svc", ApiKeySecretRef: secret, PropagationCheck: &propagationConfig{DoHURL: "google"}}, false, false},
+		{"nameserver without port", Config{Service: "svc", ApiKeySecretRef: secret, PropagationCheck: &propagationConfig{Nameservers: []string{"10.0.0.10"}}}, false, false},
+		{"bad recordNameTemplate", Config{Service: "svc", ApiKeySecretRef: secret, RecordNameTemplate: "{{ .Record"}, false, false},
+		{"unknown apiVersion", Config{Service: "svc", ApiKeySecretRef: secret, APIVersion: "v9"}, false, false},
+		{"unknown provider", Config{Service: "svc", CredentialSource: credentialSource{Provider: "foo"}}, true, false},
+	}
+	for _, test := range tests {
+		err := c.validate(&test.cfg, test.ambient)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: "_acme-challenge.www.internal.example.com.",
+		ResolvedZone: "example.com.",
+	}
+	tests := []struct {
+		zoneName, challengeZone, template, expected string
+		fails                                       bool
+	}{
+		{zoneName: "internal.example.com", expected: "_acme-challenge.www"},
+		{zoneName: "internal.example.com.", expected: "_acme-challenge.www"},
+		{zoneName: "example.net", fails: true},
+		{challengeZone: "acme.example.net", expected: "_acme-challenge.www.internal.example.com"},
+		{zoneName: "internal.example.com", template: "acme-{{ .Record }}", expected: "acme-_acme-challenge.www"},
+		{zoneName: "example.com", template: "{{ .FQDN }}@{{ .Zone }}", expected: "_acme-challenge.www.internal.example.com@example.com"},
+		{zoneName: "example.com", template: "{{ .Missing }}", fails: true},
+		{zoneName: "example.com", template: "{{ if false }}x{{ end }}", fails: true},
+	}
+	for _, test := range tests {
+		cfg := Config{ZoneName: test.zoneName, ChallengeZone: test.challengeZone, RecordNameTemplate: test.template}
+		target, err := New().resolveTarget(context.Background(), ch, &cfg)
+		if test.fails {
+			if err == nil || (test.template == "" && !errors.Is(err, ErrZoneMismatch)) {
+				t.Errorf("resolveTarget with zone %q, template %q: unexpected error %v", test.zoneName, test.template, err)
+			}
+			continue
+		}
+		if err != nil || target.record != test.expected {
+			t.Errorf("resolveTarget with zone %q = %q, %v; expected %q", test.zoneName, target.record, err, test.expected)
+		}
+	}
+}
+
+// newTestSolver returns a Solver that keeps challenges in a state
+// ConfigMap and writes to a fresh in-memory Nexus server, which only
+// accepts the key "secret", held base64-encoded in Secret default/nexus.
+// The challenge it returns is for www.example.com, with a config using
+// that Secret, service svc and zone example.com, and any fields in cfgJSON
+// set on top.
+func newTestSolver(tb testing.TB, cfgJSON string) (*Solver, *nexustest.Server, *v1alpha1.ChallengeRequest) {
+	tb.Helper()
+	server := nexustest.NewServer()
+	server.Keys = [][]byte{[]byte("secret")}
+	kube := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nexus", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("c2VjcmV0")},
+	})
+	c := New()
+	c.client = kube
+	c.store = &configMapStore{client: kube, namespace: "cert-manager", name: "nexus-challenges"}
+	c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) {
+		return server.Client(domain, service, key)
+	}
+
+	fields := map[string]json.RawMessage{}
+	base := `{"service": "svc", "zoneName": "example.com", "apikeysecret": {"name": "nexus", "key": "key"}}`
+	if err := json.Unmarshal([]byte(base), &fields); err != nil {
+		tb.Fatal(err)
+	}
+	if cfgJSON != "" {
+		if err := json.Unmarshal([]byte(cfgJSON), &fields); err != nil {
+			tb.Fatalf("invalid test config: %v", err)
+		}
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	ch := &v1alpha1.ChallengeRequest{
+		UID:               "test",
+		ResourceNamespace: "default",
+		DNSName:           "www.example.com",
+		ResolvedFQDN:      "_acme-challenge.www.example.com.",
+		ResolvedZone:      "example.com.",
+		Key:               "token",
+		Config:            &extapi.JSON{Raw: raw},
+	}
+	return c, server, ch
+}
+
+func TestPresentCleanUp(t *testing.T) {
+	c, server, ch := newTestSolver(t, `{"retry": {"initialBackoff": "1ms"}}`)
+
+	server.FailNext(errors.New("503 Service Unavailable"))
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if err := c.Present(ch); err != nil {
+		t.Fatalf("repeated Present: %v", err)
+	}
+	records := server.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %v", records)
+	}
+	for _, r := range records {
+		expected := nexustest.Record{Domain: "example.com", Service: "svc", Name: "_acme-challenge.www", Value: "token"}
+		if r != expected {
+			t.Errorf("expected record %+v, got %+v", expected, r)
+		}
+	}
+
+	if err := c.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Errorf("expected record to be deleted, got %v", records)
+	}
+
+	var ops []string
+	for _, req := range server.Requests() {
+		ops = append(ops, req.Op)
+	}
+	expected := []string{nexustest.OpCreate, nexustest.OpCreate, nexustest.OpDelete}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("expected calls %v, got %v", expected, ops)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	c := New(WithName("nexus-staging"), WithDefaultConfig([]byte(`{"service": "staging", "zoneName": "example.com"}`)))
+	cfg, err := c.config(&extapi.JSON{Raw: []byte(`{"service": "override"}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Service != "override" || cfg.ZoneName != "example.com" {
+		t.Errorf("expected issuer fields to override defaults, got %+v", cfg)
+	}
+	if cfg, err = c.config(nil); err != nil || cfg.Service != "staging" {
+		t.Errorf("expected defaults without an issuer config, got %+v, %v", cfg, err)
+	}
+
+	c = New(WithDefaultConfig([]byte(`{"servce": "typo"}`)))
+	if _, err := c.config(nil); err == nil {
+		t.Errorf("expected unknown fields in defaults to be rejected")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig(&extapi.JSON{Raw: []byte(`{
+		"service": "svc",
+		"apikeysecret": {"name": "nexus", "key": "key", "namespace": "shared"},
+		"retry": {"maxAttempts": 5, "initialBackoff": "2s"},
+		"zones": [{"zone": "example.com", "apikeysecret": {"name": "other", "key": "key"}}]
+	}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Service != "svc" || cfg.ApiKeySecretRef.Name != "nexus" || cfg.ApiKeySecretRef.Namespace != "shared" || cfg.Retry.InitialBackoff.Duration != 2*time.Second {
+		t.Errorf("config not decoded as expected: %+v", cfg)
+	}
+
+	_, err = loadConfig(&extapi.JSON{Raw: []byte(`{
+		"service": "svc",
+		"apiKey": "x",
+		"apikeysecret": {"name": "nexus", "keyName": "key"},
+		"zones": [{"zone": "example.com", "secret": {}}]
+	}`)})
+	if err == nil {
+		t.Fatalf("expected unknown fields to be rejected")
+	}
+	for _, field := range []string{"apiKey", "apikeysecret.keyName", "zones[0].secret"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected error to mention %s, got: %v", field, err)
+		}
+	}
+}
+
+func TestConcurrentPresent(t *testing.T) {
+	server := nexustest.NewServer()
+	c := New()
+	c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) {
+		return server.Client(domain, service, key)
+	}
+	os.Setenv("NEXUS_API_KEY", "secret")
+	defer os.Unsetenv("NEXUS_API_KEY")
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:            "_acme-challenge.example.com.",
+		ResolvedZone:            "example.com.",
+		Key:                     "token",
+		AllowAmbientCredentials: true,
+		Config:                  &extapi.JSON{Raw: []byte(`{"service": "svc", "zoneName": "example.com"}`)},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Present(ch); err != nil {
+				t.Errorf("Present: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if records := server.Records(); len(records) != 1 {
+		t.Errorf("expected concurrent Presents to create one record, got %d", len(records))
+	}
+}
+
+func TestPresentWildcardAndApex(t *testing.T) {
+	server := nexustest.NewServer()
+	c := New()
+	c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) {
+		return server.Client(domain, service, key)
+	}
+	os.Setenv("NEXUS_API_KEY", "secret")
+	defer os.Unsetenv("NEXUS_API_KEY")
+
+	challenge := func(dnsName, key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			DNSName:                 dnsName,
+			ResolvedFQDN:            "_acme-challenge.example.com.",
+			ResolvedZone:            "example.com.",
+			Key:                     key,
+			AllowAmbientCredentials: true,
+			Config:                  &extapi.JSON{Raw: []byte(`{"service": "svc", "zoneName": "example.com"}`)},
+		}
+	}
+	apex := challenge("example.com", "apex-token")
+	wildcard := challenge("*.example.com", "wildcard-token")
+
+	for _, ch := range []*v1alpha1.ChallengeRequest{apex, wildcard} {
+		if err := c.Present(ch); err != nil {
+			t.Fatalf("Present %s: %v", ch.DNSName, err)
+		}
+	}
+	values := func() (values []string) {
+		for _, r := range server.Records() {
+			values = append(values, r.Value)
+		}
+		sort.Strings(values)
+		return
+	}
+	if v := values(); !reflect.DeepEqual(v, []string{"apex-token", "wildcard-token"}) {
+		t.Fatalf("expected both values to be published, got %v", v)
+	}
+
+	if err := c.CleanUp(apex); err != nil {
+		t.Fatalf("CleanUp apex: %v", err)
+	}
+	if v := values(); !reflect.DeepEqual(v, []string{"wildcard-token"}) {
+		t.Errorf("expected only the wildcard value to remain, got %v", v)
+	}
+	if err := c.CleanUp(wildcard); err != nil {
+		t.Fatalf("CleanUp wildcard: %v", err)
+	}
+	if v := values(); len(v) != 0 {
+		t.Errorf("expected no values to remain, got %v", v)
+	}
+}
+
+func TestSharedStateAcrossReplicas(t *testing.T) {
+	server := nexustest.NewServer()
+	client := fake.NewSimpleClientset()
+	replica := func() *Solver {
+		c := New(WithClient(client))
+		c.newClient = func(domain, service string, key []byte) (nexusclient.API, error) {
+			return server.Client(domain, service, key)
+		}
+		c.store = &configMapStore{client: client, namespace: "cert-manager", name: "nexus-challenges"}
+		return c
+	}
+	a, b := replica(), replica()
+	os.Setenv("NEXUS_API_KEY", "secret")
+	defer os.Unsetenv("NEXUS_API_KEY")
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:            "_acme-challenge.example.com.",
+		ResolvedZone:            "example.com.",
+		Key:                     "token",
+		AllowAmbientCredentials: true,
+		Config:                  &extapi.JSON{Raw: []byte(`{"service": "svc", "zoneName": "example.com"}`)},
+	}
+
+	if err := a.Present(ch); err != nil {
+		t.Fatalf("Present on a: %v", err)
+	}
+	if err := b.Present(ch); err != nil {
+		t.Fatalf("Present on b: %v", err)
+	}
+	if records := server.Records(); len(records) != 1 {
+		t.Fatalf("expected b to reuse a's record, got %v", records)
+	}
+
+	if err := b.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp on b: %v", err)
+	}
+	if records := server.Records(); len(records) != 0 {
+		t.Fatalf("expected b to delete a's record, got %v", records)
+	}
+
+	// a still remembers the record in memory, but the store says it's gone.
+	if err := a.Present(ch); err != nil {
+		t.Fatalf("second Present on a: %v", err)
+	}
+	if records := server.Records(); len(records) != 1 {
+		t.Errorf("expected a to present a new record, got %v", records)
+	}
+}
@@ -0,0 +1,114 @@
+package solver
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+const (
+	actionPresent = "present"
+	actionCleanUp = "cleanup"
+)
+
+// RunStandalone runs a single Present or CleanUp outside Kubernetes, so
+// operators can check Nexus connectivity and credentials before deploying.
+// The API key comes from the ambient providers (--api-key-file or
+// $NEXUS_API_KEY), since there is no cluster to read Secrets from.
+func RunStandalone(action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ExitOnError)
+	fqdn := fs.String("fqdn", "", "Challenge record name, e.g. _acme-challenge.example.com.")
+	key := fs.String("key", "", "Challenge TXT record value.")
+	zone := fs.String("zone", "", "Zone containing the record. Looked up in DNS if empty.")
+	service := fs.String("service", "", "Nexus service, overriding the one in --config.")
+	config := fs.String("config", "", "Solver config as JSON, as it would appear in the Issuer.")
+	keyFile := fs.String("api-key-file", "", "File holding the Nexus API key. Defaults to $NEXUS_API_KEY.")
+	id := fs.String("id", "", "Challenge ID printed by present; required for cleanup.")
+	fs.Parse(args)
+
+	if *fqdn == "" || *key == "" {
+		return errors.New("--fqdn and --key are required")
+	}
+	settings := DefaultSettings()
+	settings.APIKeyFile = *keyFile
+	p, err := NewProcess(settings)
+	if err != nil {
+		return err
+	}
+	c := New(WithProcess(p))
+
+	cfg := map[string]interface{}{}
+	if *config != "" {
+		if err := json.Unmarshal([]byte(*config), &cfg); err != nil {
+			return fmt.Errorf("invalid --config: %w", err)
+		}
+	}
+	if *service != "" {
+		cfg["service"] = *service
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	ch := &v1alpha1.ChallengeRequest{
+		UID:                     "standalone",
+		DNSName:                 strings.TrimPrefix(util.UnFqdn(*fqdn), "_acme-challenge."),
+		Key:                     *key,
+		ResolvedFQDN:            util.ToFqdn(*fqdn),
+		ResolvedZone:            util.ToFqdn(*zone),
+		AllowAmbientCredentials: true,
+		Config:                  &extapi.JSON{Raw: raw},
+	}
+	if *zone == "" {
+		ch.ResolvedZone, err = p.zones.find(p.baseContext(), ch.ResolvedFQDN)
+		if err != nil {
+			return fmt.Errorf("could not find zone for %s, set --zone: %w", ch.ResolvedFQDN, err)
+		}
+	}
+
+	ck := newChallengeKey(ch)
+
+	switch action {
+	case actionPresent:
+		ch.Action = v1alpha1.ChallengeActionPresent
+		if err := c.Present(ch); err != nil {
+			return err
+		}
+		fmt.Println(c.challenges[ck].id)
+		return nil
+	case actionCleanUp:
+		ch.Action = v1alpha1.ChallengeActionCleanUp
+		challengeId, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("--id must be the challenge ID printed by present: %w", err)
+		}
+		c.challenges = map[ChallengeKey]trackedChallenge{ck: {id: challengeId}}
+		return c.CleanUp(ch)
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// StandaloneAction reports whether the command line asks for a standalone
+// action rather than the webhook server.
+func StandaloneAction() (string, bool) {
+	if len(os.Args) < 2 {
+		return "", false
+	}
+	switch os.Args[1] {
+	case actionPresent, actionCleanUp:
+		return os.Args[1], true
+	}
+	return "", false
+}
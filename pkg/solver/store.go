@@ -0,0 +1,204 @@
+package solver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// StateStore persists challenge record IDs outside the process, so that
+// records presented before a webhook restart, or by another replica, can
+// still be cleaned up. The webhook uses a ConfigMap or NexusChallenge
+// resources, as its settings pick; embedders can plug in another with
+// WithStateStore.
+type StateStore interface {
+	// Get returns the entry for ck, and whether there is one.
+	Get(ctx context.Context, ck ChallengeKey) (sc StoredChallenge, ok bool, err error)
+	// List returns every entry, ordered by when it was presented.
+	List(ctx context.Context) ([]StoredChallenge, error)
+	// Put stores sc for ck, replacing any existing entry.
+	Put(ctx context.Context, ck ChallengeKey, sc StoredChallenge) error
+	// Claim stores sc for ck unless the store already holds an entry for
+	// it, and returns the entry the store ends up with. Replicas racing to
+	// present the same challenge settle on one record this way.
+	Claim(ctx context.Context, ck ChallengeKey, sc StoredChallenge) (held StoredChallenge, err error)
+	// Delete removes the entry for ck, if there is one.
+	Delete(ctx context.Context, ck ChallengeKey) error
+}
+
+// configMapStoreVerbs are the verbs configMapStore uses on its ConfigMap.
+var configMapStoreVerbs = []string{"get", "create", "update"}
+
+// configMapStore keeps every challenge in one ConfigMap.
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// StoredChallenge is what the store keeps per challenge: the record ID and
+// the request that presented it, so the record can still be cleaned up if
+// cert-manager never asks. Entries written before requests were stored
+// only hold an ID.
+type StoredChallenge struct {
+	ID          uuid.UUID                  `json:"id"`
+	PresentedAt time.Time                  `json:"presentedAt,omitempty"`
+	Request     *v1alpha1.ChallengeRequest `json:"request,omitempty"`
+	// Solver names the solver that presented the record, since several may
+	// share the store. Empty means the default solver.
+	Solver string `json:"solver,omitempty"`
+	// Zone is the Nexus domain the record was created in.
+	Zone string `json:"zone,omitempty"`
+	// Backend is "fallback" if the record is in the issuer's fallback
+	// service, and empty for the primary one.
+	Backend string `json:"backend,omitempty"`
+}
+
+func (sc StoredChallenge) solver() string {
+	if sc.Solver == "" {
+		return defaultName
+	}
+	return sc.Solver
+}
+
+func parseStoredChallenge(value string) (sc StoredChallenge, err error) {
+	if strings.HasPrefix(value, "{") {
+		err = json.Unmarshal([]byte(value), &sc)
+		return
+	}
+	sc.ID, err = uuid.Parse(value)
+	return
+}
+
+// StoreKey maps a challenge to a valid ConfigMap data key and NexusChallenge
+// name. FQDNs and keys are hashed together since neither is guaranteed to
+// be a legal key.
+func (ck ChallengeKey) StoreKey() string {
+	sum := sha256.Sum256([]byte(ck.fqdn + "/" + ck.key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FQDN is the name the challenge's TXT record is served at.
+func (ck ChallengeKey) FQDN() string {
+	return ck.fqdn
+}
+
+func (s *configMapStore) Get(ctx context.Context, ck ChallengeKey) (sc StoredChallenge, ok bool, err error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	value, ok := cm.Data[ck.StoreKey()]
+	if !ok {
+		return
+	}
+	sc, err = parseStoredChallenge(value)
+	if err != nil {
+		ok = false
+		err = fmt.Errorf("invalid challenge stored for %s: %w", ck.fqdn, err)
+	}
+	return
+}
+
+// List returns every stored challenge, ordered by when it was presented.
+// Unreadable entries are skipped.
+func (s *configMapStore) List(ctx context.Context) ([]StoredChallenge, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]StoredChallenge, 0, len(cm.Data))
+	for _, value := range cm.Data {
+		if sc, err := parseStoredChallenge(value); err == nil {
+			stored = append(stored, sc)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].PresentedAt.Before(stored[j].PresentedAt) })
+	return stored, nil
+}
+
+func (s *configMapStore) Put(ctx context.Context, ck ChallengeKey, sc StoredChallenge) error {
+	value, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, func(data map[string]string) {
+		data[ck.StoreKey()] = string(value)
+	})
+}
+
+func (s *configMapStore) Claim(ctx context.Context, ck ChallengeKey, sc StoredChallenge) (held StoredChallenge, err error) {
+	value, err := json.Marshal(sc)
+	if err != nil {
+		return
+	}
+	err = s.update(ctx, func(data map[string]string) {
+		held = sc
+		if existing, ok := data[ck.StoreKey()]; ok {
+			if prev, err := parseStoredChallenge(existing); err == nil {
+				held = prev
+				return
+			}
+		}
+		data[ck.StoreKey()] = string(value)
+	})
+	return
+}
+
+func (s *configMapStore) Delete(ctx context.Context, ck ChallengeKey) error {
+	return s.update(ctx, func(data map[string]string) {
+		delete(data, ck.StoreKey())
+	})
+}
+
+// update applies mutate to the ConfigMap's data, creating the ConfigMap if
+// it doesn't exist yet and retrying on write conflicts with other callers.
+func (s *configMapStore) update(ctx context.Context, mutate func(map[string]string)) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+				Data:       map[string]string{},
+			}
+			mutate(cm.Data)
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		mutate(cm.Data)
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,67 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	store := &configMapStore{
+		client:    fake.NewSimpleClientset(),
+		namespace: "cert-manager",
+		name:      "nexus-challenges",
+	}
+	ctx := context.Background()
+	ck := ChallengeKey{fqdn: "_acme-challenge.example.com.", key: "token"}
+
+	if _, ok, err := store.Get(ctx, ck); err != nil || ok {
+		t.Fatalf("expected no stored challenge, got ok=%v err=%v", ok, err)
+	}
+
+	id := uuid.New()
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: ck.fqdn, Key: ck.key}
+	if err := store.Put(ctx, ck, StoredChallenge{ID: id, PresentedAt: time.Now(), Request: ch}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Put(ctx, ChallengeKey{fqdn: ck.fqdn, key: "other"}, StoredChallenge{ID: uuid.New()}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	got, ok, err := store.Get(ctx, ck)
+	if err != nil || !ok {
+		t.Fatalf("expected stored challenge, got ok=%v err=%v", ok, err)
+	}
+	if got.ID != id || got.Request == nil || got.Request.Key != ck.key {
+		t.Errorf("expected id %s with its request, got %+v", id, got)
+	}
+
+	if held, err := store.Claim(ctx, ck, StoredChallenge{ID: uuid.New()}); err != nil || held.ID != id {
+		t.Errorf("expected claim to return the existing entry %s, got %+v, %v", id, held, err)
+	}
+
+	stored, err := store.List(ctx)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("expected two stored challenges, got %v, %v", stored, err)
+	}
+
+	if err := store.Delete(ctx, ck); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok, err := store.Get(ctx, ck); err != nil || ok {
+		t.Fatalf("expected challenge to be deleted, got ok=%v err=%v", ok, err)
+	}
+
+	// Entries written before requests were stored hold a bare ID.
+	legacy := ChallengeKey{fqdn: ck.fqdn, key: "legacy"}
+	store.update(ctx, func(data map[string]string) { data[legacy.StoreKey()] = id.String() })
+	if got, ok, err := store.Get(ctx, legacy); err != nil || !ok || got.ID != id {
+		t.Errorf("expected legacy entry to be read, got %+v ok=%v err=%v", got, ok, err)
+	}
+}
@@ -0,0 +1,58 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var consecutiveFailures = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "consecutive_failures",
+	Help:      "Nexus writes that have failed in a row, by operation and zone; reset by the next success.",
+}, []string{"operation", "zone"})
+
+// failureStreaks counts how many Nexus writes in a row have failed for
+// each operation and zone, so a zone whose credentials or delegation broke
+// shows up before its certificates expire.
+type failureStreaks struct {
+	// warnEvery logs a warning each time a streak reaches a multiple of
+	// it. Zero disables the warning.
+	warnEvery int
+
+	lock   sync.Mutex
+	counts map[[2]string]int
+}
+
+// observe records the outcome of an operation's Nexus write to zone.
+// Cancelled calls say nothing about the zone and are ignored.
+func (s *failureStreaks) observe(operation, zone string, err error, log logr.Logger) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	key := [2]string{operation, zone}
+	s.lock.Lock()
+	if s.counts == nil {
+		s.counts = make(map[[2]string]int)
+	}
+	n := 0
+	if err != nil {
+		n = s.counts[key] + 1
+	}
+	if n == 0 {
+		delete(s.counts, key)
+	} else {
+		s.counts[key] = n
+	}
+	s.lock.Unlock()
+
+	consecutiveFailures.WithLabelValues(operation, zone).Set(float64(n))
+	if threshold := s.warnEvery; threshold > 0 && n > 0 && n%threshold == 0 {
+		log.Info("WARNING: Nexus writes keep failing for this zone; check its credentials and delegation",
+			"operation", operation, "zone", zone, "consecutiveFailures", n, "error", err.Error())
+	}
+}
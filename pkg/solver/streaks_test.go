@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFailureStreaks(t *testing.T) {
+	var warnings []string
+	log := funcr.New(func(prefix, args string) { warnings = append(warnings, args) }, funcr.Options{})
+	s := failureStreaks{warnEvery: 2}
+	gauge := consecutiveFailures.WithLabelValues(opPresent, "streaks.example.com")
+	fail := errors.New("401 Unauthorized")
+
+	for i := 1; i <= 4; i++ {
+		s.observe(opPresent, "streaks.example.com", fail, log)
+		if got := testutil.ToFloat64(gauge); got != float64(i) {
+			t.Fatalf("after %d failures the gauge is %v", i, got)
+		}
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[1], `"consecutiveFailures"=4`) {
+		t.Errorf("expected a warning at 2 and 4 failures, got %q", warnings)
+	}
+
+	s.observe(opPresent, "streaks.example.com", fmt.Errorf("abandoned: %w", context.Canceled), log)
+	if got := testutil.ToFloat64(gauge); got != 4 {
+		t.Errorf("expected a cancelled call not to count, got %v", got)
+	}
+	s.observe(opPresent, "streaks.example.com", nil, log)
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Errorf("expected a success to reset the gauge, got %v", got)
+	}
+}
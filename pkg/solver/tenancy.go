@@ -0,0 +1,94 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// tenantPolicy lets a challenge through if any rule matches it, so each
+// tenant's namespaces can be limited to their own zones and services even
+// if they reference a key that would work elsewhere.
+type tenantPolicy struct {
+	Rules []tenantRule `json:"rules"`
+}
+
+type tenantRule struct {
+	// Namespaces the rule applies to; "*" matches any. ClusterIssuers'
+	// challenges are in cert-manager's cluster resource namespace.
+	Namespaces []string `json:"namespaces"`
+	// Zones and Services, if set, are the only ones the namespaces may use.
+	Zones    []string `json:"zones,omitempty"`
+	Services []string `json:"services,omitempty"`
+}
+
+// tenantPolicies holds the process's tenant policy, if it has one.
+type tenantPolicies struct {
+	log    logr.Logger
+	lock   sync.RWMutex
+	policy *tenantPolicy
+}
+
+// load reads the policy from path, keeping the current policy if the file
+// is invalid. Empty path leaves every challenge unrestricted.
+func (t *tenantPolicies) load(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read tenant policy: %w", err)
+	}
+	var policy tenantPolicy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&policy); err != nil {
+		return fmt.Errorf("error decoding tenant policy: %w", err)
+	}
+	for i, rule := range policy.Rules {
+		if len(rule.Namespaces) == 0 {
+			return fmt.Errorf("invalid tenant policy: rules[%d].namespaces is required", i)
+		}
+	}
+	if len(policy.Rules) == 0 {
+		return errors.New("invalid tenant policy: no rules, so every challenge would be refused")
+	}
+
+	t.lock.Lock()
+	t.policy = &policy
+	t.lock.Unlock()
+	t.log.Info("loaded tenant policy", "rules", len(policy.Rules))
+	return nil
+}
+
+// check refuses a record at fqdn in service for an issuer in namespace
+// unless a rule allows it.
+func (t *tenantPolicies) check(namespace, fqdn, service string) error {
+	t.lock.RLock()
+	policy := t.policy
+	t.lock.RUnlock()
+	if policy == nil {
+		return nil
+	}
+	for _, rule := range policy.Rules {
+		if !slices.Contains(rule.Namespaces, namespace) && !slices.Contains(rule.Namespaces, "*") {
+			continue
+		}
+		if len(rule.Zones) > 0 {
+			if _, ok := matchZone(fqdn, rule.Zones); !ok {
+				continue
+			}
+		}
+		if len(rule.Services) > 0 && !slices.Contains(rule.Services, service) {
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: the tenant policy doesn't let namespace %q use service %q for %s", ErrZoneNotAllowed, namespace, service, fqdn)
+}
@@ -0,0 +1,50 @@
+package solver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTenantPolicy(t *testing.T) {
+	var tenants tenantPolicies
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(`{"rules": [
+		{"namespaces": ["team-a"], "zones": ["a.example.com"], "services": ["svc-a"]},
+		{"namespaces": ["*"], "zones": ["shared.example.com"]}
+	]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenants.load(file); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		namespace, fqdn, service string
+		allowed                  bool
+	}{
+		{"team-a", "_acme-challenge.www.a.example.com.", "svc-a", true},
+		{"team-a", "_acme-challenge.www.a.example.com.", "svc-b", false},
+		{"team-b", "_acme-challenge.www.a.example.com.", "svc-a", false},
+		{"team-b", "_acme-challenge.shared.example.com.", "svc-b", true},
+		{"team-a", "_acme-challenge.b.example.com.", "svc-a", false},
+	}
+	for _, test := range tests {
+		err := tenants.check(test.namespace, test.fqdn, test.service)
+		if (err == nil) != test.allowed || (err != nil && !errors.Is(err, ErrZoneNotAllowed)) {
+			t.Errorf("%s using %s for %s: allowed=%v, got %v", test.namespace, test.service, test.fqdn, test.allowed, err)
+		}
+	}
+
+	// A broken edit keeps the last good policy.
+	if err := os.WriteFile(file, []byte(`{"rules": [{"zones": ["example.com"]}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenants.load(file); err == nil {
+		t.Error("expected a rule without namespaces to be rejected")
+	}
+	if err := tenants.check("team-a", "_acme-challenge.www.a.example.com.", "svc-a"); err != nil {
+		t.Errorf("expected the previous policy to stay in force, got %v", err)
+	}
+}
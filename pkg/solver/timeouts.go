@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errNexusCallAbandoned is returned, wrapping the context's error, by a
+// Nexus call given up on while it was still running.
+var errNexusCallAbandoned = errors.New("nexus call abandoned")
+
+// withOperationTimeout bounds a whole Present or CleanUp by the issuer's
+// configured timeout, or fallback if it sets none. Zero means no limit
+// beyond each call's own timeout.
+func withOperationTimeout(ctx context.Context, configured *metav1.Duration, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := fallback
+	if configured != nil {
+		timeout = configured.Duration
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withKubeTimeout bounds a Kubernetes API call made on behalf of ctx.
+func (p *Process) withKubeTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.settings.KubeCallTimeout)
+}
+
+// callNexus runs op once p's rate and concurrency limits allow, giving up
+// once ctx is done or NexusCallTimeout passes. The Nexus client doesn't
+// accept a context, so an abandoned call keeps running in the background
+// until it returns on its own, and holds its concurrency slot until then.
+func callNexus[T any](ctx context.Context, p *Process, op func() (T, error)) (T, error) {
+	return callNexusLate(ctx, p, op, nil)
+}
+
+// callNexusLate is callNexus, but if the call is abandoned and later
+// returns, its result is logged and, unless late is nil, passed to late in
+// the background. Calls that create something use late to undo it, since
+// nothing else learns the ID.
+func callNexusLate[T any](ctx context.Context, p *Process, op func() (T, error), late func(T, error)) (T, error) {
+	if err := p.waitForNexusToken(ctx); err != nil {
+		var zero T
+		return zero, fmt.Errorf("waiting for nexus rate limit: %w", err)
+	}
+	release, err := p.acquireNexusSlot(ctx)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("waiting for a nexus call slot: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.settings.NexusCallTimeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	start := time.Now()
+	done := make(chan result)
+	abandoned := make(chan struct{})
+	go func() {
+		value, err := op()
+		// Release before late runs, since it may call Nexus itself.
+		release()
+		select {
+		case done <- result{value, err}:
+			return
+		case <-abandoned:
+		}
+		log := contextLogger(ctx).WithValues("after", time.Since(start))
+		if err != nil {
+			log.Error(err, "abandoned nexus call failed")
+		} else {
+			log.Info("abandoned nexus call succeeded")
+		}
+		if late != nil {
+			late(value, err)
+		}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		close(abandoned)
+		var zero T
+		return zero, fmt.Errorf("%w: %w", errNexusCallAbandoned, ctx.Err())
+	}
+}
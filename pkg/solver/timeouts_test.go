@@ -0,0 +1,127 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCallNexusTimeout(t *testing.T) {
+	s := DefaultSettings()
+	s.NexusCallTimeout = 10 * time.Millisecond
+	p := newProcess(s)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := callNexus(context.Background(), p, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	v, err := callNexus(context.Background(), p, func() (int, error) { return 42, nil })
+	if err != nil || v != 42 {
+		t.Errorf("expected 42, got %d (%v)", v, err)
+	}
+}
+
+func TestCallNexusLateResult(t *testing.T) {
+	s := DefaultSettings()
+	s.NexusCallTimeout = 10 * time.Millisecond
+	p := newProcess(s)
+
+	release := make(chan struct{})
+	late := make(chan int, 1)
+	_, err := callNexusLate(context.Background(), p, func() (int, error) {
+		<-release
+		return 7, nil
+	}, func(v int, err error) {
+		late <- v
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	close(release)
+	select {
+	case v := <-late:
+		if v != 7 {
+			t.Errorf("expected the late result 7, got %d", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the abandoned call's result to be handed on")
+	}
+
+	_, err = callNexusLate(context.Background(), p, func() (int, error) { return 1, nil }, func(int, error) {
+		t.Error("expected no late call for a result that was waited for")
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCallNexusConcurrency(t *testing.T) {
+	s := DefaultSettings()
+	s.MaxConcurrentChallenges = 2
+	// Keep the rate limit from spacing the calls out.
+	s.NexusQPS = 0
+	proc := newProcess(s)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := callNexus(context.Background(), proc, func() (struct{}, error) {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+				return struct{}{}, nil
+			})
+			if err != nil {
+				t.Errorf("callNexus: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Errorf("expected at most 2 calls at once, saw %d", got)
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	tests := []struct {
+		configured *metav1.Duration
+		fallback   time.Duration
+		expected   time.Duration
+	}{
+		{nil, 0, 0},
+		{nil, time.Minute, time.Minute},
+		{&metav1.Duration{Duration: time.Second}, time.Minute, time.Second},
+	}
+	for _, test := range tests {
+		ctx, cancel := withOperationTimeout(context.Background(), test.configured, test.fallback)
+		deadline, ok := ctx.Deadline()
+		switch {
+		case test.expected == 0 && ok:
+			t.Errorf("withOperationTimeout(%v, %s): expected no deadline, got %s", test.configured, test.fallback, deadline)
+		case test.expected != 0 && (!ok || time.Until(deadline) > test.expected):
+			t.Errorf("withOperationTimeout(%v, %s): expected a deadline within %s, got %s", test.configured, test.fallback, test.expected, deadline)
+		}
+		cancel()
+	}
+}
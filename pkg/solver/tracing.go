@@ -0,0 +1,65 @@
+package solver
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is resolved through the global provider, so spans started before
+// setupTracing runs (or when tracing is disabled) are no-ops.
+var tracer = otel.Tracer("github.com/fudoniten/cert-manager-webhook-nexus")
+
+// setupTracing installs a tracer provider exporting to the
+// OTLPTracesEndpoint, flushing remaining spans once stopCh is closed.
+func (p *Process) setupTracing(stopCh <-chan struct{}) {
+	endpoint := p.settings.OTLPTracesEndpoint
+	if endpoint == "" {
+		return
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "cert-manager-webhook-nexus"
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(endpoint),
+		otlptracehttp.WithTimeout(10*time.Second))
+	if err != nil {
+		p.log.Error(err, "tracing disabled: invalid --otlp-traces-endpoint", "endpoint", endpoint)
+		return
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			p.log.Error(err, "failed to flush traces")
+		}
+	}()
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
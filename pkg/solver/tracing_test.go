@@ -0,0 +1,74 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSetupTracing(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	s := DefaultSettings()
+	s.OTLPTracesEndpoint = srv.URL + "/v1/traces"
+	stopCh := make(chan struct{})
+	newProcess(s).setupTracing(stopCh)
+
+	ctx, parent := tracer.Start(context.Background(), "Present")
+	_, child := tracer.Start(ctx, "GetSecret")
+	child.SetAttributes(attribute.String("k8s.namespace.name", "default"))
+	endSpan(child, errors.New("forbidden"))
+	parent.End()
+	// Closing stopCh flushes the spans.
+	close(stopCh)
+
+	var spans []*tracepb.Span
+	select {
+	case req := <-requests:
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the spans to be exported on shutdown")
+	}
+	if len(spans) != 2 {
+		t.Fatalf("expected two spans, got %v", spans)
+	}
+	span := spans[0]
+	if span.Name != "GetSecret" || string(span.ParentSpanId) != string(spans[1].SpanId) {
+		t.Errorf("unexpected span %v", span)
+	}
+	if span.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || span.Status.GetMessage() != "forbidden" {
+		t.Errorf("expected error status, got %v", span.Status)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Errorf("expected the recorded error as an event, got %v", span.Events)
+	}
+	if len(span.Attributes) != 1 || span.Attributes[0].Value.GetStringValue() != "default" {
+		t.Errorf("unexpected attributes %v", span.Attributes)
+	}
+}
@@ -0,0 +1,87 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields lists the paths of keys in data, a generically decoded JSON
+// value, that have no matching field in t. Matching is case-insensitive,
+// like encoding/json. Types with their own UnmarshalJSON aren't inspected.
+func unknownFields(data interface{}, t reflect.Type, path string) (unknown []string) {
+	if t.Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(jsonUnmarshaler) {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ft, ok := fields[strings.ToLower(k)]
+			if !ok {
+				unknown = append(unknown, path+k)
+				continue
+			}
+			unknown = append(unknown, unknownFields(obj[k], ft, path+k+".")...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := data.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			unknown = append(unknown, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(path, "."), i))...)
+		}
+	}
+	return
+}
+
+// jsonFields maps the lowercased JSON names of t's fields to their types,
+// including fields promoted from embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,200 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+const (
+	defaultVaultAuthMount = "kubernetes"
+	serviceAccountToken   = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+func init() {
+	registerCredentialProvider(providerVault, func(c *Solver) CredentialProvider { return &vaultClient{c: c} })
+}
+
+// vaultConfig locates an API key in Vault, logging in with the webhook's
+// service account through Vault's Kubernetes auth method.
+type vaultConfig struct {
+	Address string `json:"address"`
+	// AuthMount is the path the Kubernetes auth method is mounted at.
+	AuthMount string `json:"authMount,omitempty"`
+	Role      string `json:"role"`
+	// Path of the secret, e.g. "secret/data/nexus" for a KV v2 engine.
+	Path string `json:"path"`
+	Key  string `json:"key"`
+}
+
+// vaultClient fetches keys from Vault, reusing login tokens until their
+// lease runs out.
+type vaultClient struct {
+	c *Solver
+
+	lock   sync.Mutex
+	tokens map[vaultLogin]vaultToken
+	// tokenFile defaults to the pod's service account token.
+	tokenFile string
+	http      *http.Client
+}
+
+type vaultLogin struct {
+	address, mount, role string
+}
+
+type vaultToken struct {
+	token   string
+	expires time.Time
+}
+
+// reload drops cached login tokens, so the next fetch logs in again.
+func (v *vaultClient) reload() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	clear(v.tokens)
+}
+
+func (v *vaultConfig) validate() error {
+	switch {
+	case v.Address == "":
+		return errors.New("vault address is required")
+	case v.Role == "":
+		return errors.New("vault role is required")
+	case v.Path == "":
+		return errors.New("vault path is required")
+	case v.Key == "":
+		return errors.New("vault key is required")
+	}
+	return nil
+}
+
+func (v *vaultClient) Ambient() bool { return false }
+
+func (v *vaultClient) APIKey(ctx context.Context, _ *v1alpha1.ChallengeRequest, solverCfg *Config) (key string, err error) {
+	cfg := solverCfg.CredentialSource.Vault
+	if cfg == nil {
+		err = errors.New("vault credential provider requires a credentialSource.vault block")
+		return
+	}
+	if err = cfg.validate(); err != nil {
+		return
+	}
+	ctx, cancel := v.c.proc.withKubeTimeout(ctx)
+	defer cancel()
+
+	token, err := v.token(ctx, cfg)
+	if err != nil {
+		return
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = v.do(ctx, http.MethodGet, cfg.Address, "/v1/"+strings.TrimPrefix(cfg.Path, "/"), token, nil, &resp)
+	if err != nil {
+		err = fmt.Errorf("failed to read vault secret %s: %w", cfg.Path, err)
+		return
+	}
+
+	data := resp.Data
+	// KV v2 nests the secret's fields under data.data.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	key, ok := data[cfg.Key].(string)
+	if !ok {
+		err = fmt.Errorf("vault secret %s has no string key %s", cfg.Path, cfg.Key)
+	}
+	return
+}
+
+func (v *vaultClient) token(ctx context.Context, cfg *vaultConfig) (string, error) {
+	mount := cfg.AuthMount
+	if mount == "" {
+		mount = defaultVaultAuthMount
+	}
+	login := vaultLogin{address: cfg.Address, mount: mount, role: cfg.Role}
+
+	v.lock.Lock()
+	cached, ok := v.tokens[login]
+	v.lock.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.token, nil
+	}
+
+	tokenFile := v.tokenFile
+	if tokenFile == "" {
+		tokenFile = serviceAccountToken
+	}
+	jwt, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role": cfg.Role, "jwt": strings.TrimSpace(string(jwt))}
+	err = v.do(ctx, http.MethodPost, cfg.Address, "/v1/auth/"+strings.Trim(mount, "/")+"/login", "", body, &resp)
+	if err != nil {
+		return "", fmt.Errorf("vault login failed: %w", err)
+	}
+
+	// Renew a little before the lease ends rather than racing it.
+	lease := time.Duration(resp.Auth.LeaseDuration) * time.Second
+	token := vaultToken{token: resp.Auth.ClientToken, expires: time.Now().Add(lease * 9 / 10)}
+	v.lock.Lock()
+	if v.tokens == nil {
+		v.tokens = map[vaultLogin]vaultToken{}
+	}
+	v.tokens[login] = token
+	v.lock.Unlock()
+	return token.token, nil
+}
+
+func (v *vaultClient) do(ctx context.Context, method, address, path, token string, body, out interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(address, "/")+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	client := v.http
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,58 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultClient(t *testing.T) {
+	logins := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role"] != "webhook" || body["jwt"] != "sa-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		logins++
+		w.Write([]byte(`{"auth": {"client_token": "vault-token", "lease_duration": 3600}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/nexus", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"apikey": "c2VjcmV0"}, "metadata": {}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v := &vaultClient{c: New(), tokenFile: tokenFile}
+	cfg := &vaultConfig{Address: server.URL, Role: "webhook", Path: "secret/data/nexus", Key: "apikey"}
+	solverCfg := &Config{CredentialSource: credentialSource{Vault: cfg}}
+
+	for i := 0; i < 2; i++ {
+		key, err := v.APIKey(context.Background(), nil, solverCfg)
+		if err != nil || key != "c2VjcmV0" {
+			t.Fatalf("expected key from vault, got %q, %v", key, err)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("expected the login token to be reused, logged in %d times", logins)
+	}
+
+	cfg.Key = "missing"
+	if _, err := v.APIKey(context.Background(), nil, solverCfg); err == nil {
+		t.Errorf("expected an error for a missing key")
+	}
+}
@@ -0,0 +1,58 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+)
+
+// Set at build time with -ldflags
+// "-X github.com/fudoniten/cert-manager-webhook-nexus/pkg/solver.version=...".
+var (
+	version   = "dev"
+	gitCommit = "unknown"
+	buildDate = "unknown"
+)
+
+// VersionInfo describes the build of the running webhook.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// BuildInfo returns the version the binary was built with.
+func BuildInfo() VersionInfo {
+	return VersionInfo{
+		Version:   version,
+		GitCommit: gitCommit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String formats v for --version.
+func (v VersionInfo) String() string {
+	return fmt.Sprintf("cert-manager-webhook-nexus %s (commit %s, built %s, %s)", v.Version, v.GitCommit, v.BuildDate, v.GoVersion)
+}
+
+// VersionRequested reports whether --version was passed. It has to be
+// checked before the webhook server parses flags, since the server expects
+// to be running in a cluster.
+func VersionRequested() bool {
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "--version", "-version", "--version=true", "-version=true":
+			return true
+		}
+	}
+	return false
+}
+
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BuildInfo())
+}
@@ -0,0 +1,136 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// findZone looks up the zone apex for a name; tests replace it.
+var findZone = findZoneBySOA
+
+// findZoneBySOA climbs fqdn's labels until a nameserver answers with a SOA
+// record, like util.FindZoneByFqdn but without its cache, which never
+// forgets a zone.
+func findZoneBySOA(ctx context.Context, fqdn string, nameservers []string) (string, error) {
+	fqdn = util.ToFqdn(fqdn)
+	for _, i := range dns.Split(fqdn) {
+		domain := fqdn[i:]
+		r, err := util.DNSQuery(ctx, domain, dns.TypeSOA, nameservers, true)
+		if err != nil {
+			return "", err
+		}
+		if r.Rcode == dns.RcodeNameError {
+			continue
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			return "", fmt.Errorf("SOA query for %s returned %s", domain, dns.RcodeToString[r.Rcode])
+		}
+		for _, rr := range r.Answer {
+			// A CNAME can't be at a zone apex, so keep climbing past one.
+			if _, ok := rr.(*dns.CNAME); ok {
+				break
+			}
+			if soa, ok := rr.(*dns.SOA); ok {
+				return soa.Hdr.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no SOA record found for %s", fqdn)
+}
+
+type cachedZone struct {
+	zone    string
+	err     error
+	expires time.Time
+}
+
+// zoneCache remembers SOA lookups, and failed ones for a shorter time, so
+// repeated issuance for the same names doesn't walk public DNS each time.
+type zoneCache struct {
+	// ttl is how long to remember a zone, and negativeTTL that there is
+	// none, for up to size names. Zero ttl disables the cache.
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+	family      ipFamily
+
+	lock    sync.Mutex
+	entries map[string]cachedZone
+}
+
+// find returns the zone apex for name, from the cache if it has a live
+// entry.
+func (zc *zoneCache) find(ctx context.Context, name string) (string, error) {
+	if zc.ttl <= 0 {
+		return findZone(ctx, name, zc.family.recursiveNameservers())
+	}
+
+	key := strings.ToLower(util.ToFqdn(name))
+	now := time.Now()
+	zc.lock.Lock()
+	entry, ok := zc.entries[key]
+	zc.lock.Unlock()
+	if ok && now.Before(entry.expires) {
+		zoneCacheLookupsTotal.WithLabelValues("hit").Inc()
+		return entry.zone, entry.err
+	}
+	zoneCacheLookupsTotal.WithLabelValues("miss").Inc()
+
+	zone, err := findZone(ctx, name, zc.family.recursiveNameservers())
+	if ctx.Err() != nil {
+		// Don't remember a lookup cut short by the caller.
+		return zone, err
+	}
+	ttl := zc.ttl
+	if err != nil {
+		ttl = zc.negativeTTL
+	}
+	if ttl <= 0 {
+		return zone, err
+	}
+
+	zc.lock.Lock()
+	defer zc.lock.Unlock()
+	if zc.entries == nil {
+		zc.entries = make(map[string]cachedZone)
+	}
+	if len(zc.entries) >= zc.size {
+		zc.evict(now)
+	}
+	if zc.size > 0 {
+		zc.entries[key] = cachedZone{zone: zone, err: err, expires: now.Add(ttl)}
+	}
+	return zone, err
+}
+
+// evict drops expired entries, or if there are none, the one closest to
+// expiring. Callers hold zc.lock.
+func (zc *zoneCache) evict(now time.Time) {
+	var oldest string
+	for k, e := range zc.entries {
+		if !now.Before(e.expires) {
+			delete(zc.entries, k)
+			continue
+		}
+		if oldest == "" || e.expires.Before(zc.entries[oldest].expires) {
+			oldest = k
+		}
+	}
+	if len(zc.entries) >= zc.size && oldest != "" {
+		delete(zc.entries, oldest)
+	}
+}
+
+// flush drops every cached zone.
+func (zc *zoneCache) flush() {
+	zc.lock.Lock()
+	defer zc.lock.Unlock()
+	clear(zc.entries)
+}
@@ -0,0 +1,63 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestZoneCache(t *testing.T) {
+	defer func(find func(context.Context, string, []string) (string, error)) { findZone = find }(findZone)
+	lookups := map[string]int{}
+	findZone = func(_ context.Context, fqdn string, _ []string) (string, error) {
+		lookups[fqdn]++
+		if fqdn == "missing.test." {
+			return "", errors.New("no SOA record found")
+		}
+		return "example.com.", nil
+	}
+	zc := &zoneCache{ttl: time.Hour, negativeTTL: time.Hour, size: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if zone, err := zc.find(ctx, "a.example.com."); err != nil || zone != "example.com." {
+			t.Fatalf("find: %q, %v", zone, err)
+		}
+		if _, err := zc.find(ctx, "missing.test."); err == nil {
+			t.Fatalf("expected the failed lookup to be returned")
+		}
+	}
+	if lookups["a.example.com."] != 1 || lookups["missing.test."] != 1 {
+		t.Errorf("expected one lookup per name, got %v", lookups)
+	}
+
+	zc.find(ctx, "b.example.com.")
+	if len(zc.entries) != 2 {
+		t.Errorf("expected the cache to stay at 2 entries, got %d", len(zc.entries))
+	}
+
+	zc.negativeTTL = 0
+	zc.flush()
+	zc.find(ctx, "missing.test.")
+	zc.find(ctx, "missing.test.")
+	if lookups["missing.test."] != 3 {
+		t.Errorf("expected failures not to be cached without a negative TTL, got %d lookups", lookups["missing.test."])
+	}
+
+	zc.ttl = 0
+	zc.find(ctx, "a.example.com.")
+	if lookups["a.example.com."] != 2 || len(zc.entries) != 0 {
+		t.Errorf("expected a lookup without the cache, got %v and %d entries", lookups, len(zc.entries))
+	}
+}
+
+func TestFindZoneBySOA(t *testing.T) {
+	addr := serveV6OnlyZone(t)
+	if zone, err := findZoneBySOA(context.Background(), "_acme-challenge.v6only.test", []string{addr}); err != nil || zone != "v6only.test." {
+		t.Errorf("expected zone v6only.test., got %q, %v", zone, err)
+	}
+	if _, err := findZoneBySOA(context.Background(), "other.test.", []string{addr}); err == nil {
+		t.Errorf("expected no zone for a name outside it")
+	}
+}